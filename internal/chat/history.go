@@ -0,0 +1,106 @@
+package chat
+
+import (
+	ctxutil "github.com/yourname/clichat/internal/context"
+	"github.com/yourname/clichat/internal/memory/sqlite"
+)
+
+// defaultHistoryTokens is the history budget when neither HISTORY_MAX_TOKENS nor the
+// model's context window is known.
+const defaultHistoryTokens = 8192
+
+// historyBudget returns how many tokens of history fit in a request to a model with a context
+// window of window tokens (0 if unknown) whose fixed part (system prompt, pinned messages)
+// already uses fixed tokens.
+func (s *Service) historyBudget(window, fixed int) int {
+	budget := s.cfg.HistoryMaxTokens
+	if window > 0 {
+		avail := window - s.cfg.AnswerReserveTokens
+		if budget <= 0 || avail < budget {
+			budget = avail
+		}
+	}
+	if budget <= 0 && window <= 0 {
+		budget = defaultHistoryTokens
+	}
+	budget -= fixed
+	if budget < 0 {
+		return 0
+	}
+	return budget
+}
+
+// historyWindow picks the most recent whole turns of messages that fit in budget tokens.
+//
+// It walks backwards from the newest message, grouping each user message with the answers
+// that follow it into a turn, and stops at the first turn that does not fit so the history
+// stays contiguous. The newest turn (normally the prompt being answered) is always included,
+// even when it alone exceeds the budget.
+//
+// Orphaned user messages — prompts that never got an answer because of an error or an
+// interruption, and were followed directly by another prompt — are dropped, so the model
+// does not try to answer a backlog of old questions. Pinned messages cost nothing here:
+// the caller accounts for them separately.
+func historyWindow(messages []sqlite.Message, budget int, count func(string) int) []sqlite.Message {
+	var turns [][]sqlite.Message
+	end := len(messages)
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != "user" && i > 0 {
+			continue
+		}
+		turn := messages[i:end]
+		end = i
+		orphan := len(turns) > 0 && len(turn) == 1 && turn[0].Role == "user"
+		if !orphan {
+			turns = append(turns, turn)
+		}
+	}
+
+	var (
+		used int
+		out  []sqlite.Message
+	)
+	for n, turn := range turns {
+		cost := 0
+		for _, m := range turn {
+			if !m.Pinned {
+				cost += count(m.Content)
+			}
+		}
+		if n > 0 && used+cost > budget {
+			break
+		}
+		used += cost
+		out = append(append([]sqlite.Message{}, turn...), out...)
+	}
+	return out
+}
+
+// withPreamble prefixes a non-empty body, such as the summary, with the text introducing it.
+func withPreamble(preamble, body string) string {
+	if body == "" {
+		return ""
+	}
+	return preamble + body
+}
+
+// messageTokens counts a history message with tok, including the chat format's per-message
+// overhead.
+func messageTokens(tok ctxutil.Tokenizer) func(string) int {
+	return func(text string) int { return tok.Count(text) + ctxutil.TokensPerMessage }
+}
+
+// fixedContents lists the contents that go into a request ahead of the history window: the
+// non-empty system texts, then the pinned messages.
+func fixedContents(pinned []sqlite.Message, system ...string) []string {
+	var out []string
+	for _, s := range system {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	for _, m := range pinned {
+		out = append(out, m.Content)
+	}
+	return out
+}
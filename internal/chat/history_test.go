@@ -0,0 +1,84 @@
+package chat
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yourname/clichat/internal/config"
+	"github.com/yourname/clichat/internal/memory/sqlite"
+)
+
+// words counts one token per message character, which keeps budgets easy to reason about.
+func words(s string) int { return len(s) }
+
+func msgs(spec ...string) []sqlite.Message {
+	out := make([]sqlite.Message, 0, len(spec)/2)
+	for i := 0; i < len(spec); i += 2 {
+		out = append(out, sqlite.Message{ID: int64(i/2 + 1), Role: spec[i], Content: spec[i+1]})
+	}
+	return out
+}
+
+func ids(ms []sqlite.Message) []int64 {
+	var out []int64
+	for _, m := range ms {
+		out = append(out, m.ID)
+	}
+	return out
+}
+
+func TestHistoryWindow(t *testing.T) {
+	history := msgs(
+		"user", "aaaa", // 1
+		"assistant", "bbbb", // 2
+		"user", "cccc", // 3
+		"assistant", "dddd", // 4
+		"user", "eeee", // 5 (current prompt)
+	)
+	cases := []struct {
+		name   string
+		msgs   []sqlite.Message
+		budget int
+		want   []int64
+	}{
+		{"everything fits", history, 100, []int64{1, 2, 3, 4, 5}},
+		{"whole turns only", history, 15, []int64{3, 4, 5}},
+		{"current prompt always sent", history, 1, []int64{5}},
+		{"orphaned prompts dropped", msgs("user", "a", "assistant", "b", "user", "lost", "user", "again", "user", "now"), 100, []int64{1, 2, 5}},
+		{"answers kept with their prompt", msgs("user", "q", "assistant", "part1", "assistant", "part2", "user", "next"), 100, []int64{1, 2, 3, 4}},
+		{"empty", nil, 100, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ids(historyWindow(tc.msgs, tc.budget, words))
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHistoryWindowPinnedIsFree(t *testing.T) {
+	history := msgs("user", "long pinned spec", "assistant", "ok", "user", "q")
+	history[0].Pinned = true
+	if got := ids(historyWindow(history, 3, words)); !reflect.DeepEqual(got, []int64{1, 2, 3}) {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestHistoryBudget(t *testing.T) {
+	cases := []struct {
+		window, max, reserve, fixed, want int
+	}{
+		{0, 0, 1024, 100, defaultHistoryTokens - 100},
+		{8000, 0, 1000, 500, 6500},
+		{8000, 2000, 1000, 500, 1500},
+		{4000, 0, 5000, 0, 0},
+	}
+	for _, tc := range cases {
+		s := &Service{cfg: &config.Config{HistoryMaxTokens: tc.max, AnswerReserveTokens: tc.reserve}}
+		if got := s.historyBudget(tc.window, tc.fixed); got != tc.want {
+			t.Errorf("%+v: got %d", tc, got)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	ctxutil "github.com/yourname/clichat/internal/context"
+	"github.com/yourname/clichat/internal/memory/sqlite"
+	"github.com/yourname/clichat/internal/provider"
+)
+
+// ContextReport is the request the next turn of a conversation would send, with token counts.
+type ContextReport struct {
+	// Request is the provider-neutral request; Payload is the body the provider would send
+	// for it, when the provider can tell (see provider.RequestEncoder).
+	Request provider.ChatRequest `json:"request"`
+	Payload json.RawMessage      `json:"payload,omitempty"`
+	// MessageTokens holds the tokens of each request message, including per-message overhead.
+	MessageTokens []int `json:"message_tokens"`
+	// PromptTokens is the whole prompt, including the tokens that prime the reply.
+	PromptTokens  int    `json:"prompt_tokens"`
+	ContextTokens int    `json:"context_tokens,omitempty"`
+	Tokenizer     string `json:"tokenizer"`
+	// Notes explain where the real request may differ from this dry run.
+	Notes []string `json:"notes,omitempty"`
+}
+
+// InspectContext builds the request for the next answer in a conversation without sending
+// anything to the provider. A non-empty prompt is included as the next user message. A
+// conversation that does not exist yet is inspected as an empty one, without creating it.
+func (s *Service) InspectContext(ctx context.Context, conversationID, prompt string) (*ContextReport, error) {
+	conv, err := s.store.GetConversation(conversationID)
+	if errors.Is(err, sqlite.ErrConversationNotFound) {
+		conv, err = &sqlite.Conversation{ID: conversationID, Title: conversationID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.buildRequest(ctx, conv, buildOptions{dryRun: true, prompt: prompt})
+	if err != nil {
+		return nil, err
+	}
+	req := b.req
+	rep := &ContextReport{
+		Request:       req,
+		PromptTokens:  estimatePromptTokens(b.tok, req.Messages),
+		ContextTokens: s.ContextTokens(ctx, req.Model),
+		Tokenizer:     b.tok.Name(),
+		Notes:         b.notes,
+	}
+	for _, m := range req.Messages {
+		rep.MessageTokens = append(rep.MessageTokens, b.tok.Count(m.Content)+ctxutil.TokensPerMessage)
+	}
+	if enc, ok := s.prov.(provider.RequestEncoder); ok {
+		if rep.Payload, err = enc.EncodeRequest(ctx, req); err != nil {
+			return nil, err
+		}
+	} else {
+		rep.Notes = append(rep.Notes, "the provider cannot show its request body: only the provider-neutral request is listed")
+	}
+	if prompt == "" {
+		rep.Notes = append(rep.Notes, "no prompt given: the next prompt will be added after the last message")
+	}
+	return rep, nil
+}
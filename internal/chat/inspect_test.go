@@ -0,0 +1,66 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yourname/clichat/internal/config"
+	"github.com/yourname/clichat/internal/memory/sqlite"
+	"github.com/yourname/clichat/internal/provider/litellm"
+	"github.com/yourname/clichat/internal/stream"
+)
+
+func TestInspectContextIsDryRun(t *testing.T) {
+	st, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+	if _, err := st.CreateOrGetConversation("c", "c"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	long := strings.Repeat("x", 400)
+	for i := 0; i < 3; i++ {
+		_, _ = st.AppendMessage("c", "user", long)
+		_, _ = st.AppendMessage("c", "assistant", long)
+	}
+
+	var reqs []litellm.ChatRequest
+	cfg := &config.Config{Model: "m", SystemPrompt: "sys", HistoryMaxTokens: 300, AutoSummarize: true, EmbeddingModel: "emb", Temperature: 0.2}
+	svc := NewService(cfg, st, fakeLiteLLM(t, "unused", &reqs), stream.NewRenderer())
+
+	rep, err := svc.InspectContext(context.Background(), "c", "next question")
+	if err != nil {
+		t.Fatalf("InspectContext: %v", err)
+	}
+	if len(reqs) != 0 {
+		t.Fatalf("dry run sent %d requests", len(reqs))
+	}
+	msgs := rep.Request.Messages
+	if len(msgs) != 4 || msgs[0].Content != "sys" || msgs[3].Content != "next question" {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+	if len(rep.MessageTokens) != len(msgs) || rep.PromptTokens <= rep.MessageTokens[3] || rep.Request.Temperature == nil || *rep.Request.Temperature != 0.2 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	if len(rep.Notes) != 2 {
+		t.Fatalf("want notes about recall and the pending summary, got %q", rep.Notes)
+	}
+	if got, _ := st.AllMessages("c"); len(got) != 6 {
+		t.Fatalf("dry run stored the prompt: %d messages", len(got))
+	}
+	// The payload is the provider's wire format, not the neutral request
+	if !strings.Contains(string(rep.Payload), `"stream_options":{"include_usage":true}`) {
+		t.Fatalf("unexpected payload: %s", rep.Payload)
+	}
+
+	if _, err := svc.InspectContext(context.Background(), "new", "hi"); err != nil {
+		t.Fatalf("InspectContext on a new conversation: %v", err)
+	}
+	if _, err := st.GetConversation("new"); !errors.Is(err, sqlite.ErrConversationNotFound) {
+		t.Fatalf("inspecting created the conversation: %v", err)
+	}
+}
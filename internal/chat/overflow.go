@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yourname/clichat/internal/config"
+	ctxutil "github.com/yourname/clichat/internal/context"
+	"github.com/yourname/clichat/internal/provider"
+)
+
+// maxOverflowRetries bounds how often a request the provider rejected as too long is trimmed
+// and sent again.
+const maxOverflowRetries = 2
+
+// OverflowError reports a request that does not fit the model's context window. Nothing was
+// sent and the prompt was not stored.
+type OverflowError struct {
+	Model string
+	// Tokens is the estimated size of the request; Available is the window minus the answer reserve.
+	Tokens    int
+	Available int
+	// Trimmed is set when the request is too large even with all older history dropped.
+	Trimmed bool
+}
+
+func (e *OverflowError) Error() string {
+	if e.Trimmed {
+		return fmt.Sprintf("request needs ~%d tokens even without older history, but %s has room for %d; shorten the prompt or unpin messages", e.Tokens, e.Model, e.Available)
+	}
+	return fmt.Sprintf("request needs ~%d tokens but %s has room for %d; nothing was sent", e.Tokens, e.Model, e.Available)
+}
+
+// preflight compares the request with the model's context window and applies policy to an
+// oversized one: warn and send it, trim older history, or refuse with an *OverflowError.
+func (s *Service) preflight(ctx context.Context, b *builtRequest, policy string) error {
+	limit := s.ContextTokens(ctx, b.req.Model)
+	if limit <= 0 {
+		return nil
+	}
+	available := limit - s.cfg.AnswerReserveTokens
+	tokens := estimatePromptTokens(b.tok, b.req.Messages)
+	if tokens <= available {
+		return nil
+	}
+	switch policy {
+	case config.OverflowWarn:
+		fmt.Printf("[warning: request is ~%d tokens, %s has room for %d]\n", tokens, b.req.Model, available)
+		return nil
+	case config.OverflowTrim:
+		msgs, dropped := trimHistory(b.req.Messages, b.historyStart, b.tok, available)
+		if n := estimatePromptTokens(b.tok, msgs); n > available {
+			return &OverflowError{Model: b.req.Model, Tokens: n, Available: available, Trimmed: true}
+		}
+		fmt.Printf("[context: dropped %d older messages to fit %s's window]\n", dropped, b.req.Model)
+		b.req.Messages = msgs
+		return nil
+	}
+	return &OverflowError{Model: b.req.Model, Tokens: tokens, Available: available}
+}
+
+// trimHistory drops the oldest history turns of msgs, which start at index start, until the
+// request fits target tokens or only the newest turn is left. It returns the messages kept and
+// how many were dropped.
+func trimHistory(msgs []provider.ChatMessage, start int, tok ctxutil.Tokenizer, target int) ([]provider.ChatMessage, int) {
+	dropped := 0
+	for estimatePromptTokens(tok, msgs) > target {
+		end := start + 1
+		for end < len(msgs) && msgs[end].Role != "user" {
+			end++
+		}
+		if end >= len(msgs) {
+			break
+		}
+		msgs = append(append([]provider.ChatMessage{}, msgs[:start]...), msgs[end:]...)
+		dropped += end - start
+	}
+	return msgs, dropped
+}
@@ -0,0 +1,111 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yourname/clichat/internal/config"
+	ctxutil "github.com/yourname/clichat/internal/context"
+	"github.com/yourname/clichat/internal/memory/sqlite"
+	"github.com/yourname/clichat/internal/provider/litellm"
+	"github.com/yourname/clichat/internal/stream"
+)
+
+func TestTrimHistory(t *testing.T) {
+	long := strings.Repeat("word ", 40)
+	msgs := []litellm.ChatMessage{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: long}, {Role: "assistant", Content: long},
+		{Role: "user", Content: long}, {Role: "assistant", Content: long},
+		{Role: "user", Content: "now"},
+	}
+	all := estimatePromptTokens(ctxutil.Heuristic, msgs)
+	if got, dropped := trimHistory(msgs, 1, ctxutil.Heuristic, all); dropped != 0 || len(got) != len(msgs) {
+		t.Fatalf("fitting request trimmed: %d dropped", dropped)
+	}
+	got, dropped := trimHistory(msgs, 1, ctxutil.Heuristic, all-10)
+	if dropped != 2 || len(got) != 4 || got[0].Role != "system" || got[1].Content != long {
+		t.Fatalf("one turn: %d dropped, %+v", dropped, got)
+	}
+	// Never drops the system prompt or the newest turn
+	got, dropped = trimHistory(msgs, 1, ctxutil.Heuristic, 1)
+	if dropped != 4 || len(got) != 2 || got[0].Role != "system" || got[1].Content != "now" {
+		t.Fatalf("all history: %d dropped, %+v", dropped, got)
+	}
+}
+
+func TestOverflowRefuseSendsNothing(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("oversized request was sent")
+	}))
+	defer ts.Close()
+	st, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+	cfg := &config.Config{Model: "m", ModelContextTokens: 200, AnswerReserveTokens: 50}
+	svc := NewService(cfg, st, litellm.NewProvider(litellm.NewClient(ts.URL, ""), nil), stream.NewRenderer())
+
+	prompt := strings.Repeat("x ", 1000)
+	for _, policy := range []string{config.OverflowRefuse, config.OverflowTrim} {
+		err := svc.SendWithOverflow(context.Background(), "c", prompt, policy)
+		var oe *OverflowError
+		if !errors.As(err, &oe) || oe.Available != 150 || oe.Tokens <= 150 {
+			t.Fatalf("%s: want overflow error, got %v", policy, err)
+		}
+		if oe.Trimmed != (policy == config.OverflowTrim) {
+			t.Errorf("%s: Trimmed = %v", policy, oe.Trimmed)
+		}
+	}
+	if msgs, _ := st.AllMessages("c"); len(msgs) != 0 {
+		t.Fatalf("refused prompt was stored: %+v", msgs)
+	}
+}
+
+func TestContextLengthExceededRetriesWithLessHistory(t *testing.T) {
+	var sizes []int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req litellm.ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		sizes = append(sizes, len(req.Messages))
+		if len(sizes) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"This model's maximum context length is 100 tokens","code":"context_length_exceeded"}}`)
+			return
+		}
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"ok\"}}]}\n\ndata: [DONE]\n\n")
+	}))
+	defer ts.Close()
+	st, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+	if _, err := st.CreateOrGetConversation("c", "c"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		_, _ = st.AppendMessage("c", "user", strings.Repeat("question ", 20))
+		_, _ = st.AppendMessage("c", "assistant", strings.Repeat("answer ", 20))
+	}
+	svc := NewService(&config.Config{Model: "m"}, st, litellm.NewProvider(litellm.NewClient(ts.URL, ""), nil), stream.NewRenderer())
+
+	if err := svc.HandleUserInput(context.Background(), "c", "and now?"); err != nil {
+		t.Fatalf("HandleUserInput: %v", err)
+	}
+	if len(sizes) != 2 || sizes[1] >= sizes[0] {
+		t.Fatalf("request sizes = %v, want a smaller retry", sizes)
+	}
+	msgs, _ := st.AllMessages("c")
+	if last := msgs[len(msgs)-1]; last.Role != "assistant" || last.Content != "ok" {
+		t.Fatalf("answer not stored: %+v", last)
+	}
+}
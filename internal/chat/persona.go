@@ -0,0 +1,73 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourname/clichat/internal/memory/sqlite"
+	"github.com/yourname/clichat/internal/persona"
+	"github.com/yourname/clichat/internal/provider"
+)
+
+// SetPersonas sets the personas conversations can use.
+func (s *Service) SetPersonas(set persona.Set) { s.personas = set }
+
+// Personas returns the personas conversations can use.
+func (s *Service) Personas() persona.Set { return s.personas }
+
+// SetPersona attaches the named persona to a conversation; an empty name detaches it.
+func (s *Service) SetPersona(conversationID, name string) error {
+	if _, ok := s.personas[name]; name != "" && !ok {
+		return fmt.Errorf("unknown persona %q (available: %s)", name, strings.Join(s.personas.Names(), ", "))
+	}
+	return s.store.SetPersona(conversationID, name)
+}
+
+// ConversationModel returns the model answering in a conversation: the persona's preferred
+// model if it has one, else the active model.
+func (s *Service) ConversationModel(conversationID string) string {
+	conv, err := s.store.GetConversation(conversationID)
+	if err != nil {
+		return s.currentModel()
+	}
+	return s.resolvePersona(conv).Model
+}
+
+// resolvePersona returns the conversation's persona with empty fields filled from the global
+// configuration. A persona that no longer exists is ignored.
+func (s *Service) resolvePersona(conv *sqlite.Conversation) persona.Persona {
+	p := s.personas[conv.Persona]
+	if p.SystemPrompt == "" {
+		p.SystemPrompt = s.cfg.SystemPrompt
+	}
+	if p.Model == "" {
+		p.Model = s.currentModel()
+	}
+	if p.Temperature == nil {
+		p.Temperature = &s.cfg.Temperature
+	}
+	if p.TopP == nil {
+		p.TopP = &s.cfg.TopP
+	}
+	return p
+}
+
+// exampleMessages turns a persona's few-shot examples into request messages.
+func exampleMessages(p persona.Persona) []provider.ChatMessage {
+	var out []provider.ChatMessage
+	for _, ex := range p.Examples {
+		out = append(out,
+			provider.ChatMessage{Role: "user", Content: ex.User},
+			provider.ChatMessage{Role: "assistant", Content: ex.Assistant})
+	}
+	return out
+}
+
+// exampleContents returns the contents of a persona's few-shot messages, for token counting.
+func exampleContents(p persona.Persona) []string {
+	var out []string
+	for _, m := range exampleMessages(p) {
+		out = append(out, m.Content)
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+package chat
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/yourname/clichat/internal/config"
+	"github.com/yourname/clichat/internal/memory/sqlite"
+	"github.com/yourname/clichat/internal/persona"
+	"github.com/yourname/clichat/internal/provider/litellm"
+	"github.com/yourname/clichat/internal/stream"
+)
+
+func TestBuildRequestAppliesPersona(t *testing.T) {
+	st, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+	if _, err := st.CreateOrGetConversation("c", "c"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	_, _ = st.AppendMessage("c", "user", "SELECT everything")
+
+	cfg := &config.Config{Model: "m", SystemPrompt: "sys", Temperature: 0.2, TopP: 1}
+	svc := NewService(cfg, st, litellm.NewProvider(litellm.NewClient("http://unused", ""), nil), stream.NewRenderer())
+	temp := 0.7
+	svc.SetPersonas(persona.Set{"sql": {
+		Name: "sql", SystemPrompt: "You write SQL.", Model: "sql-model", Temperature: &temp,
+		Examples: []persona.Example{{User: "count users", Assistant: "SELECT COUNT(*) FROM users;"}},
+	}})
+	if err := svc.SetPersona("c", "nope"); err == nil {
+		t.Fatal("expected an error for an unknown persona")
+	}
+	if err := svc.SetPersona("c", "sql"); err != nil {
+		t.Fatalf("SetPersona: %v", err)
+	}
+
+	conv, _ := st.GetConversation("c")
+	b, err := svc.buildRequest(context.Background(), conv, buildOptions{})
+	if err != nil {
+		t.Fatalf("buildRequest: %v", err)
+	}
+	req := b.req
+	// persona prompt, example turn, history
+	if len(req.Messages) != 4 || req.Messages[0].Content != "You write SQL." || req.Messages[1].Content != "count users" ||
+		req.Messages[2].Role != "assistant" || req.Messages[3].Content != "SELECT everything" || b.historyStart != 3 {
+		t.Fatalf("unexpected messages: %+v", req.Messages)
+	}
+	if req.Model != "sql-model" || req.Temperature == nil || *req.Temperature != 0.7 || req.TopP == nil || *req.TopP != 1 {
+		t.Fatalf("persona settings not applied: model %s, temperature %v, top_p %v", req.Model, req.Temperature, req.TopP)
+	}
+	if got := svc.ConversationModel("c"); got != "sql-model" {
+		t.Fatalf("ConversationModel = %q", got)
+	}
+
+	// Detaching restores the global settings
+	if err := svc.SetPersona("c", ""); err != nil {
+		t.Fatalf("SetPersona: %v", err)
+	}
+	conv, _ = st.GetConversation("c")
+	if b, _ = svc.buildRequest(context.Background(), conv, buildOptions{}); b.req.Model != "m" || b.req.Messages[0].Content != "sys" || len(b.req.Messages) != 2 {
+		t.Fatalf("global settings not restored: %+v", b.req)
+	}
+}
@@ -0,0 +1,107 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yourname/clichat/internal/memory/sqlite"
+	"github.com/yourname/clichat/internal/provider"
+)
+
+const recallPreamble = "Recalled memory: excerpts from the user's earlier, separate conversations that may be relevant. " +
+	"They can be outdated or unrelated; use them only when they help answer the current prompt.\n"
+
+const (
+	// embedBatch is how many not yet embedded messages are indexed per embeddings request.
+	embedBatch = 64
+	// maxEmbedChars caps the text sent for one embedding.
+	maxEmbedChars = 8000
+	// maxRecallChars caps each recalled excerpt in a request.
+	maxRecallChars = 1000
+)
+
+// ErrRecallDisabled is returned by Recall when no EMBEDDING_MODEL is configured.
+var ErrRecallDisabled = errors.New("recall is disabled: set EMBEDDING_MODEL")
+
+// Recall returns the messages of other conversations most similar to query. Messages that
+// have no embedding yet are indexed in the background, so recent ones may be missed.
+func (s *Service) Recall(ctx context.Context, conversationID, query string) ([]sqlite.MemoryHit, error) {
+	model := s.cfg.EmbeddingModel
+	if model == "" {
+		return nil, ErrRecallDisabled
+	}
+	emb, ok := s.prov.(provider.Embedder)
+	if !ok {
+		return nil, fmt.Errorf("recall: the %s provider does not support embeddings", s.cfg.Provider)
+	}
+	s.startIndexing(emb, model)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	vecs, err := emb.Embed(ctx, model, []string{truncate(query, maxEmbedChars)})
+	if err != nil {
+		return nil, err
+	}
+	return s.store.SimilarMessages(vecs[0], sqlite.SimilarOptions{
+		Model: model, K: s.cfg.RecallTopK, MinScore: s.cfg.RecallMinScore,
+		ExcludeConversation: conversationID, Window: s.cfg.RecallWindow,
+	})
+}
+
+// startIndexing embeds every message that has no embedding for model yet in a background
+// goroutine, newest first, unless that is already running. It stops at the first failure;
+// the next Recall picks up where it left off.
+func (s *Service) startIndexing(emb provider.Embedder, model string) {
+	if !s.indexing.TryLock() {
+		return
+	}
+	go func() {
+		defer s.indexing.Unlock()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			n, err := s.indexMemory(ctx, emb, model)
+			cancel()
+			if err != nil || n < embedBatch {
+				return
+			}
+		}
+	}()
+}
+
+// indexMemory embeds one batch of the newest messages that have no embedding for model yet and
+// returns how many it embedded.
+func (s *Service) indexMemory(ctx context.Context, emb provider.Embedder, model string) (int, error) {
+	pending, err := s.store.UnembeddedMessages(model, embedBatch)
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+	inputs := make([]string, len(pending))
+	for i, m := range pending {
+		inputs[i] = truncate(m.Content, maxEmbedChars)
+	}
+	vecs, err := emb.Embed(ctx, model, inputs)
+	if err != nil {
+		return 0, err
+	}
+	for i, m := range pending {
+		if err := s.store.SaveEmbedding(m.ID, model, vecs[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(pending), nil
+}
+
+// formatRecall renders hits as the body of the recalled memory system message.
+func formatRecall(hits []sqlite.MemoryHit) string {
+	var sb strings.Builder
+	for _, h := range hits {
+		source := h.ConversationID
+		if h.ConversationTitle != "" && h.ConversationTitle != h.ConversationID {
+			source = h.ConversationTitle
+		}
+		fmt.Fprintf(&sb, "- [%s, %s] %s: %s\n", source, h.CreatedAt.Format("2006-01-02"), roleName(h.Role), truncate(h.Content, maxRecallChars))
+	}
+	return sb.String()
+}
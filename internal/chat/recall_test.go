@@ -0,0 +1,89 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yourname/clichat/internal/config"
+	"github.com/yourname/clichat/internal/memory/sqlite"
+	"github.com/yourname/clichat/internal/provider/litellm"
+	"github.com/yourname/clichat/internal/stream"
+)
+
+// topicVector embeds text by counting two topic words, so similarity follows the topic.
+func topicVector(text string) []float32 {
+	return []float32{float32(strings.Count(text, "pasta")), float32(strings.Count(text, "golang")), 0.01}
+}
+
+func TestBuildRequestRecallsOtherConversations(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		var out struct {
+			Data []item `json:"data"`
+		}
+		for i, in := range req.Input {
+			out.Data = append(out.Data, item{i, topicVector(in)})
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	}))
+	defer ts.Close()
+	st, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+	for _, id := range []string{"cooking", "code", "c"} {
+		if _, err := st.CreateOrGetConversation(id, id); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	_, _ = st.AppendMessage("cooking", "user", "my pasta recipe uses anchovies")
+	_, _ = st.AppendMessage("code", "user", "golang generics question")
+	_, _ = st.AppendMessage("c", "user", "what goes into my pasta?")
+
+	cfg := &config.Config{Model: "m", EmbeddingModel: "emb", RecallTopK: 3, RecallMinScore: 0.5}
+	svc := NewService(cfg, st, litellm.NewProvider(litellm.NewClient(ts.URL, ""), nil), stream.NewRenderer())
+	// Background indexing must finish before the store closes
+	defer svc.indexing.Lock()
+	conv, _ := st.GetConversation("c")
+	// The first request starts indexing in the background instead of waiting for it
+	if _, err := svc.buildRequest(context.Background(), conv, buildOptions{}); err != nil {
+		t.Fatalf("buildRequest: %v", err)
+	}
+	svc.indexing.Lock()
+	svc.indexing.Unlock()
+	if p, _ := st.UnembeddedMessages("emb", 10); len(p) != 0 {
+		t.Fatalf("not indexed: %+v", p)
+	}
+	b, err := svc.buildRequest(context.Background(), conv, buildOptions{})
+	if err != nil {
+		t.Fatalf("buildRequest: %v", err)
+	}
+	if len(b.req.Messages) != 2 || !strings.HasPrefix(b.req.Messages[0].Content, recallPreamble) {
+		t.Fatalf("no recalled memory: %+v", b.req.Messages)
+	}
+	if mem := b.req.Messages[0].Content; !strings.Contains(mem, "anchovies") || strings.Contains(mem, "golang") {
+		t.Fatalf("wrong memory recalled: %q", mem)
+	}
+
+	// Turned off for the conversation, nothing is recalled
+	if err := st.SetRecall("c", false); err != nil {
+		t.Fatalf("set recall: %v", err)
+	}
+	conv, _ = st.GetConversation("c")
+	if b, _ := svc.buildRequest(context.Background(), conv, buildOptions{}); len(b.req.Messages) != 1 {
+		t.Fatalf("recall not disabled: %+v", b.req.Messages)
+	}
+}
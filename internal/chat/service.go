@@ -4,96 +4,179 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"strings"
+	"sync"
 
 	"github.com/yourname/clichat/internal/config"
 	ctxutil "github.com/yourname/clichat/internal/context"
 	"github.com/yourname/clichat/internal/memory/sqlite"
-	"github.com/yourname/clichat/internal/provider/litellm"
+	"github.com/yourname/clichat/internal/persona"
+	"github.com/yourname/clichat/internal/pricing"
+	"github.com/yourname/clichat/internal/provider"
 	"github.com/yourname/clichat/internal/stream"
 )
 
 type Service struct {
 	cfg   *config.Config
 	store *sqlite.Store
-	prov  *litellm.Client
+	prov  provider.Provider
 	r     *stream.Renderer
+	// prices from PRICING_FILE take precedence over the provider's
+	prices pricing.Table
+	// personas from PERSONAS_DIR, applied per conversation
+	personas persona.Set
+	// titleTried holds the conversations a title was requested for in this session
+	titleTried sync.Map
+	// indexing is held while memory is being indexed in the background
+	indexing sync.Mutex
 }
 
-func NewService(cfg *config.Config, store *sqlite.Store, prov *litellm.Client, r *stream.Renderer) *Service {
+func NewService(cfg *config.Config, store *sqlite.Store, prov provider.Provider, r *stream.Renderer) *Service {
 	return &Service{cfg: cfg, store: store, prov: prov, r: r}
 }
 
+// ModelInfo returns what the provider reports about model; ok is false when it does not know
+// the model.
+func (s *Service) ModelInfo(ctx context.Context, model string) (info provider.ModelInfo, ok bool) {
+	return s.prov.ModelInfo(ctx, model)
+}
+
+// SetPricing sets local model prices, which override the pricing the provider reports.
+func (s *Service) SetPricing(t pricing.Table) { s.prices = t }
+
+// Price returns the pricing of model; ok is false when neither the local table nor the provider
+// knows it.
+func (s *Service) Price(ctx context.Context, model string) (p pricing.Price, ok bool) {
+	if p, ok := s.prices[model]; ok {
+		return p, true
+	}
+	info, ok := s.ModelInfo(ctx, model)
+	if !ok || (info.InputCostPerToken == 0 && info.OutputCostPerToken == 0) {
+		return pricing.Price{}, false
+	}
+	return pricing.Price{Input: info.InputCostPerToken, Output: info.OutputCostPerToken}, true
+}
+
+// ContextTokens returns the context window of model: MODEL_CONTEXT_TOKENS when set, else the
+// input limit the provider reports for the model, else 0 (unknown).
+func (s *Service) ContextTokens(ctx context.Context, model string) int {
+	if s.cfg.ModelContextTokens > 0 {
+		return s.cfg.ModelContextTokens
+	}
+	info, _ := s.ModelInfo(ctx, model)
+	return info.MaxInputTokens
+}
+
 func (s *Service) HandleUserInput(ctx context.Context, conversationID string, text string) error {
+	return s.SendWithOverflow(ctx, conversationID, text, s.cfg.ContextOverflow)
+}
+
+// SendWithOverflow is HandleUserInput with an explicit context overflow policy (see
+// config.ContextOverflow). The prompt is stored only once the request passes the overflow
+// check, so a refused prompt leaves the conversation unchanged.
+func (s *Service) SendWithOverflow(ctx context.Context, conversationID string, text string, policy string) error {
 	// Always reset color on exit so user prompt returns to default/white
 	defer fmt.Print("\x1b[0m")
 
 	if conversationID == "" {
 		return errors.New("conversation id required")
 	}
-	if _, err := s.store.CreateOrGetConversation(conversationID, conversationID); err != nil {
+	conv, err := s.store.CreateOrGetConversation(conversationID, conversationID)
+	if err != nil {
 		return err
 	}
-	// Persist only the current user message now
-	if _, err := s.store.AppendMessage(conversationID, "user", text); err != nil {
+	return s.respond(ctx, conv, respondOptions{prompt: text, overflow: policy})
+}
+
+// Retry discards the last answer of a conversation, keeping it as a variant, and streams a new
+// answer to the same prompt. A non-empty model overrides the active model for this answer only.
+func (s *Service) Retry(ctx context.Context, conversationID string, model string) error {
+	defer fmt.Print("\x1b[0m")
+
+	conv, err := s.store.GetConversation(conversationID)
+	if err != nil {
 		return err
 	}
-	// Load up to 200 recent messages for context
-	messages, err := s.store.ListMessages(conversationID, 200)
+	messages, err := s.store.AllMessages(conversationID)
 	if err != nil {
 		return err
 	}
-	var reqMsgs []litellm.ChatMessage
-	if s.cfg.SystemPrompt != "" {
-		reqMsgs = append(reqMsgs, litellm.ChatMessage{Role: "system", Content: s.cfg.SystemPrompt})
+	if len(messages) == 0 {
+		return ErrNothingToRetry
 	}
-	// Prefer relevant tail of history. If we have any assistant replies, include from the last assistant onward.
-	// If we only have user messages (e.g., due to prior bug or interruptions), include only the last 1-2 user messages
-	// to avoid the model re-answering the entire backlog repeatedly.
-	lastAssistant := -1
-	for i := len(messages) - 1; i >= 0; i-- {
-		if messages[i].Role == "assistant" {
-			lastAssistant = i
-			break
+	last := messages[len(messages)-1]
+	if last.Role == "assistant" {
+		if err := s.store.SupersedeFrom(last.ID); err != nil {
+			return err
 		}
 	}
-	if lastAssistant >= 0 {
-		for _, m := range messages[lastAssistant:] {
-			reqMsgs = append(reqMsgs, litellm.ChatMessage{Role: m.Role, Content: m.Content})
-		}
-	} else {
-		start := len(messages) - 2
-		if start < 0 {
-			start = 0
-		}
-		for _, m := range messages[start:] {
-			if m.Role == "user" {
-				reqMsgs = append(reqMsgs, litellm.ChatMessage{Role: m.Role, Content: m.Content})
-			}
-		}
+	return s.respond(ctx, conv, respondOptions{model: model, overflow: s.cfg.ContextOverflow})
+}
+
+// Edit replaces the last user message of a conversation with text, keeping the original and its
+// answer as variants, and streams a new answer.
+func (s *Service) Edit(ctx context.Context, conversationID string, text string) error {
+	defer fmt.Print("\x1b[0m")
+
+	conv, err := s.store.GetConversation(conversationID)
+	if err != nil {
+		return err
 	}
-	// resolve model: state overrides env if present
-	model := s.cfg.Model
-	if st, err := config.LoadState(); err == nil {
-		if st.Model != "" {
-			model = st.Model
+	last, err := s.LastUserMessage(conversationID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.store.ReplaceMessage(last.ID, &sqlite.Message{Role: "user", Content: text}); err != nil {
+		return err
+	}
+	return s.respond(ctx, conv, respondOptions{overflow: s.cfg.ContextOverflow})
+}
+
+// LastUserMessage returns the most recent current user message of a conversation.
+func (s *Service) LastUserMessage(conversationID string) (*sqlite.Message, error) {
+	messages, err := s.store.AllMessages(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return &messages[i], nil
 		}
 	}
-	tools := []litellm.Tool{}
-	if s.cfg.EnableProviderWebsearch {
-		tools = append(tools, litellm.Tool{Type: "web_search"})
+	return nil, ErrNothingToRetry
+}
+
+// ErrNothingToRetry is returned by Retry and Edit when the conversation has no prompt yet.
+var ErrNothingToRetry = errors.New("no previous prompt in this conversation")
+
+// respondOptions tunes respond.
+type respondOptions struct {
+	// model overrides the active model for this answer.
+	model string
+	// prompt is a new user message, stored once the request passed the overflow check.
+	prompt string
+	// overflow is the context overflow policy for this answer.
+	overflow string
+}
+
+// respond builds the request from the conversation history, streams the answer and stores it.
+func (s *Service) respond(ctx context.Context, conv *sqlite.Conversation, opts respondOptions) error {
+	conversationID := conv.ID
+	b, err := s.buildRequest(ctx, conv, buildOptions{model: opts.model, prompt: opts.prompt})
+	if err != nil {
+		return err
 	}
-	// Build request with conditional sampling params
-	req := litellm.ChatRequest{
-		Model:    model,
-		Messages: reqMsgs,
-		Stream:   true,
-		Tools:    tools,
+	if err := s.preflight(ctx, b, opts.overflow); err != nil {
+		return err
 	}
-	if !(s.cfg.DropSamplingParams || strings.HasPrefix(model, "gpt-5")) {
-		req.Temperature = s.cfg.Temperature
-		req.TopP = s.cfg.TopP
+	if opts.prompt != "" {
+		if _, err := s.store.AppendMessage(conversationID, "user", opts.prompt); err != nil {
+			return err
+		}
 	}
+	req, messages, tok := b.req, b.messages, b.tok
+	model := req.Model
 	if s.cfg.DebugPrompts {
 		fmt.Println("\n[debug] prompt context:")
 		for i, m := range req.Messages {
@@ -101,54 +184,258 @@ func (s *Service) HandleUserInput(ctx context.Context, conversationID string, te
 		}
 	}
 
-	promptTokens := estimatePromptTokens(reqMsgs)
-	deltas, errs := s.prov.StreamChat(ctx, req)
-	var assistant string
-	saved := false
-	saveAssistant := func() int {
-		if saved || assistant == "" {
-			return 0
+	var (
+		assistant string
+		usage     *provider.Usage
+	)
+	for retries := 0; ; retries++ {
+		assistant, usage, err = s.streamAnswer(ctx, req)
+		if err == nil || assistant != "" || retries == maxOverflowRetries || !provider.IsContextLengthExceeded(err) {
+			break
+		}
+		// The provider counts differently than we do: drop a quarter of the request and retry
+		msgs, dropped := trimHistory(req.Messages, b.historyStart, tok, estimatePromptTokens(tok, req.Messages)*3/4)
+		if dropped == 0 {
+			break
 		}
-		_, _ = s.store.AppendMessage(conversationID, "assistant", assistant)
-		tokens := ctxutil.EstimateTokens(assistant)
-		_ = s.store.UpdateContextUsage(conversationID, promptTokens, tokens)
-		saved = true
-		return tokens
+		fmt.Printf("[context: the provider rejected the request as too long, retrying without %d older messages]\n", dropped)
+		req.Messages = msgs
 	}
+	if assistant != "" {
+		// Keep partial answers too, with local estimates when the provider reported no usage
+		u := usage
+		if u == nil {
+			u = &provider.Usage{PromptTokens: estimatePromptTokens(tok, req.Messages), CompletionTokens: tok.Count(assistant)}
+		}
+		if u.TotalTokens == 0 {
+			u.TotalTokens = u.PromptTokens + u.CompletionTokens
+		}
+		price, _ := s.Price(ctx, model)
+		_, _ = s.store.InsertMessage(&sqlite.Message{
+			ConversationID: conversationID, Role: "assistant", Content: assistant, Model: model,
+			PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, TotalTokens: u.TotalTokens,
+			Cost: price.Cost(u.PromptTokens, u.CompletionTokens),
+		})
+		_ = s.store.UpdateContextUsage(conversationID, u.PromptTokens, u.CompletionTokens)
+		if err == nil {
+			if s.cfg.AutoTitle && !conv.TitleLocked && conv.Title == conv.ID && s.claimTitle(conversationID) {
+				go s.generateTitle(conversationID, lastUserContent(messages), assistant)
+			}
+			if limit := s.ContextTokens(ctx, model); limit > 0 {
+				fmt.Printf("  [context: %d/%d (%s)]\n", u.TotalTokens, limit, ctxutil.PercentUsed(u.TotalTokens, limit))
+			} else {
+				fmt.Println()
+			}
+		}
+	}
+	// The provider's message does not always name the model, so record which one was asked for
+	var notFound *provider.ModelNotFoundError
+	if errors.As(err, &notFound) && notFound.Model == "" {
+		notFound.Model = model
+	}
+	return err
+}
+
+// streamAnswer streams the answer to req to the terminal. It returns the answer, which is
+// partial when err is set, and the usage the provider reported, if any.
+func (s *Service) streamAnswer(ctx context.Context, req provider.ChatRequest) (string, *provider.Usage, error) {
+	deltas, usage, errs := s.prov.StreamChatUsage(ctx, req)
+	var assistant strings.Builder
 	for {
 		select {
 		case d, ok := <-deltas:
 			if !ok {
-				answerTokens := saveAssistant()
-				if assistant != "" {
-					if s.cfg.ModelContextTokens > 0 {
-						used := promptTokens + answerTokens
-						fmt.Printf("  [context: %d/%d (%s)]\n", used, s.cfg.ModelContextTokens, ctxutil.PercentUsed(used, s.cfg.ModelContextTokens))
-					} else {
-						fmt.Println()
-					}
+				// The usage channel is closed before deltas, so this never blocks
+				if u, ok := <-usage; ok {
+					return assistant.String(), &u, nil
 				}
-				return nil
+				return assistant.String(), nil, nil
 			}
-			assistant += d
+			assistant.WriteString(d)
 			_ = s.r.WriteToken(d)
 		case err := <-errs:
 			if err != nil {
-				saveAssistant()
-				return err
+				return assistant.String(), nil, err
 			}
 			// nil error: ignore and continue
 		case <-ctx.Done():
-			saveAssistant()
-			return ctx.Err()
+			return assistant.String(), nil, ctx.Err()
+		}
+	}
+}
+
+// buildOptions tunes buildRequest.
+type buildOptions struct {
+	// model overrides the active model.
+	model string
+	// dryRun builds the request without calling the provider: the summary is left as it is and
+	// memory is not recalled; notes record what was skipped.
+	dryRun bool
+	// prompt is added as an unsaved user message after the history.
+	prompt string
+}
+
+// builtRequest is a request together with what went into it.
+type builtRequest struct {
+	req provider.ChatRequest
+	// messages are the conversation's current messages, including an unsaved prompt.
+	messages []sqlite.Message
+	tok      ctxutil.Tokenizer
+	// historyStart is the index of the first history message in req.Messages; the messages
+	// before it (system prompt, summary, memory, pinned) are always sent.
+	historyStart int
+	notes        []string
+}
+
+// buildRequest assembles the chat request for the next answer in a conversation: system prompt,
+// running summary, recalled memory, pinned messages and the recent turns that fit the history
+// budget.
+func (s *Service) buildRequest(ctx context.Context, conv *sqlite.Conversation, opts buildOptions) (*builtRequest, error) {
+	messages, err := s.store.AllMessages(conv.ID)
+	if err != nil {
+		return nil, err
+	}
+	pinned, err := s.store.ListPinned(conv.ID)
+	if err != nil {
+		return nil, err
+	}
+	if opts.prompt != "" {
+		messages = append(messages, sqlite.Message{ID: math.MaxInt64, ConversationID: conv.ID, Role: "user", Content: opts.prompt})
+	}
+	p := s.resolvePersona(conv)
+	model := opts.model
+	if model == "" {
+		model = p.Model
+	}
+	tok := ctxutil.ForModel(model)
+	var notes []string
+	var memory string
+	if s.cfg.EmbeddingModel != "" && conv.Recall {
+		if opts.dryRun {
+			notes = append(notes, "recalled memory is not shown: it needs an embeddings request")
+		} else if hits, err := s.Recall(ctx, conv.ID, lastUserContent(messages)); err == nil {
+			// Recall is best effort: a failing embeddings endpoint must not block the chat
+			memory = formatRecall(hits)
+		}
+	}
+	fixed := ctxutil.CountMessages(tok, fixedContents(pinned, append(exampleContents(p), p.SystemPrompt, withPreamble(summaryPreamble, conv.Summary), withPreamble(recallPreamble, memory))...))
+	window := historyWindow(messages, s.historyBudget(s.ContextTokens(ctx, model), fixed), messageTokens(tok))
+	summary := conv.Summary
+	if s.cfg.AutoSummarize {
+		if pending := pendingSummary(conv, messages, window); opts.dryRun && len(pending) > 0 {
+			notes = append(notes, fmt.Sprintf("messages #%d-#%d fell out of the window and will be folded into the summary first",
+				pending[0].ID, pending[len(pending)-1].ID))
+		} else if !opts.dryRun {
+			// Fold turns that just fell out of the window into the summary; on failure keep the old one
+			if sum, err := s.updateSummary(ctx, conv, messages, window); err == nil {
+				summary = sum
+			}
+		}
+	}
+
+	var reqMsgs []provider.ChatMessage
+	if p.SystemPrompt != "" {
+		reqMsgs = append(reqMsgs, provider.ChatMessage{Role: "system", Content: p.SystemPrompt})
+	}
+	if summary != "" {
+		reqMsgs = append(reqMsgs, provider.ChatMessage{Role: "system", Content: summaryPreamble + summary})
+	}
+	if memory != "" {
+		reqMsgs = append(reqMsgs, provider.ChatMessage{Role: "system", Content: recallPreamble + memory})
+	}
+	// Few-shot examples lead into the real conversation
+	reqMsgs = append(reqMsgs, exampleMessages(p)...)
+	// Pinned messages always go first, unless the window already includes them
+	inWindow := make(map[int64]bool, len(window))
+	for _, m := range window {
+		inWindow[m.ID] = true
+	}
+	for _, m := range pinned {
+		if !inWindow[m.ID] {
+			reqMsgs = append(reqMsgs, provider.ChatMessage{Role: m.Role, Content: m.Content})
+		}
+	}
+	historyStart := len(reqMsgs)
+	for _, m := range window {
+		reqMsgs = append(reqMsgs, provider.ChatMessage{Role: m.Role, Content: m.Content})
+	}
+	tools := []provider.Tool{}
+	if s.cfg.EnableProviderWebsearch {
+		tools = append(tools, provider.Tool{Type: "web_search"})
+	}
+	// Build request with conditional sampling params
+	req := provider.ChatRequest{
+		Model:    model,
+		Messages: reqMsgs,
+		Stream:   true,
+		Tools:    tools,
+	}
+	if !(s.cfg.DropSamplingParams || strings.HasPrefix(model, "gpt-5")) {
+		// Leave out parameters the model is known to reject
+		info, _ := s.ModelInfo(ctx, model)
+		if info.Supports("temperature") {
+			req.Temperature = p.Temperature
+		}
+		if info.Supports("top_p") {
+			req.TopP = p.TopP
+		}
+	}
+	return &builtRequest{req: req, messages: messages, tok: tok, historyStart: historyStart, notes: notes}, nil
+}
+
+// currentModel resolves the active model: state overrides env if present.
+func (s *Service) currentModel() string {
+	model := s.cfg.Model
+	if st, err := config.LoadState(); err == nil {
+		if st.Model != "" {
+			model = st.Model
+		}
+	}
+	return model
+}
+
+// complete runs a request to completion and returns the concatenated answer. Its usage is
+// recorded as a kind request of the conversation, estimated when the provider reports none.
+func (s *Service) complete(ctx context.Context, conversationID, kind string, req provider.ChatRequest) (string, error) {
+	deltas, usage, errs := s.prov.StreamChatUsage(ctx, req)
+	var sb strings.Builder
+	for d := range deltas {
+		sb.WriteString(d)
+	}
+	if err := <-errs; err != nil {
+		return "", err
+	}
+	out := sb.String()
+	u, ok := <-usage
+	if !ok {
+		tok := ctxutil.ForModel(req.Model)
+		u = provider.Usage{PromptTokens: estimatePromptTokens(tok, req.Messages), CompletionTokens: tok.Count(out)}
+	}
+	if u.TotalTokens == 0 {
+		u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	}
+	price, _ := s.Price(ctx, req.Model)
+	_ = s.store.RecordUsage(&sqlite.UsageRecord{
+		ConversationID: conversationID, Kind: kind, Model: req.Model,
+		PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, TotalTokens: u.TotalTokens,
+		Cost: price.Cost(u.PromptTokens, u.CompletionTokens),
+	})
+	return out, nil
+}
+
+func lastUserContent(msgs []sqlite.Message) string {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == "user" {
+			return msgs[i].Content
 		}
 	}
+	return ""
 }
 
-func estimatePromptTokens(msgs []litellm.ChatMessage) int {
+func estimatePromptTokens(tok ctxutil.Tokenizer, msgs []provider.ChatMessage) int {
 	contents := make([]string, 0, len(msgs))
 	for _, m := range msgs {
 		contents = append(contents, m.Content)
 	}
-	return ctxutil.EstimateTokensForContents(contents)
+	return ctxutil.CountMessages(tok, contents)
 }
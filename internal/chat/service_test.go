@@ -0,0 +1,65 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/yourname/clichat/internal/config"
+	"github.com/yourname/clichat/internal/memory/sqlite"
+	"github.com/yourname/clichat/internal/provider/litellm"
+	"github.com/yourname/clichat/internal/stream"
+)
+
+func TestHandleUserInputStoresUsage(t *testing.T) {
+	for _, reported := range []bool{true, false} {
+		t.Run(fmt.Sprint("reported=", reported), func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"four\"}}]}\n\n")
+				if reported {
+					fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":21,\"completion_tokens\":2,\"total_tokens\":23}}\n\n")
+				}
+				fmt.Fprint(w, "data: [DONE]\n\n")
+			}))
+			defer ts.Close()
+			st, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			defer st.Close()
+			svc := NewService(&config.Config{Model: "m"}, st, litellm.NewProvider(litellm.NewClient(ts.URL, ""), nil), stream.NewRenderer())
+
+			if err := svc.HandleUserInput(context.Background(), "c", "2+2?"); err != nil {
+				t.Fatalf("HandleUserInput: %v", err)
+			}
+			msgs, err := st.AllMessages("c")
+			if err != nil || len(msgs) != 2 {
+				t.Fatalf("messages: %+v, %v", msgs, err)
+			}
+			a := msgs[1]
+			conv, _ := st.GetConversation("c")
+			if reported {
+				if a.PromptTokens != 21 || a.CompletionTokens != 2 || a.TotalTokens != 23 {
+					t.Fatalf("usage not stored: %+v", a)
+				}
+				if conv.ContextPromptTokens != 21 || conv.ContextAnswerTokens != 2 {
+					t.Fatalf("context usage not updated: %+v", conv)
+				}
+				return
+			}
+			if a.PromptTokens == 0 || a.CompletionTokens != 1 || a.TotalTokens != a.PromptTokens+1 {
+				t.Fatalf("estimates not stored: %+v", a)
+			}
+		})
+	}
+}
+
+func TestClaimTitleOncePerConversation(t *testing.T) {
+	svc := NewService(&config.Config{}, nil, nil, nil)
+	if !svc.claimTitle("a") || svc.claimTitle("a") || !svc.claimTitle("b") {
+		t.Fatal("want one titling attempt per conversation")
+	}
+}
@@ -0,0 +1,146 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	ctxutil "github.com/yourname/clichat/internal/context"
+	"github.com/yourname/clichat/internal/memory/sqlite"
+	"github.com/yourname/clichat/internal/provider"
+)
+
+// summaryPreamble introduces the running summary injected after the system prompt.
+const summaryPreamble = "Summary of the earlier part of this conversation (older messages are not shown):\n"
+
+const summarizerPrompt = "You maintain a running summary of a conversation between a user and an assistant. " +
+	"Merge the new messages into the current summary. Keep facts, decisions, constraints, names, code identifiers " +
+	"and open questions; drop pleasantries and repetition. Reply with the updated summary only, at most 300 words."
+
+// maxSummaryChunkTokens bounds how much history is sent to the summarizer per request.
+const maxSummaryChunkTokens = 6000
+
+// Resummarize discards the running summary and rebuilds it from every message that no longer
+// fits the history window. It returns the new summary, which is empty when everything fits.
+func (s *Service) Resummarize(ctx context.Context, conversationID string) (string, error) {
+	conv, err := s.store.GetConversation(conversationID)
+	if err != nil {
+		return "", err
+	}
+	conv.Summary, conv.SummaryThrough = "", 0
+	messages, err := s.store.AllMessages(conversationID)
+	if err != nil {
+		return "", err
+	}
+	pinned, err := s.store.ListPinned(conversationID)
+	if err != nil {
+		return "", err
+	}
+	p := s.resolvePersona(conv)
+	tok := ctxutil.ForModel(p.Model)
+	fixed := ctxutil.CountMessages(tok, fixedContents(pinned, append(exampleContents(p), p.SystemPrompt)...))
+	window := historyWindow(messages, s.historyBudget(s.ContextTokens(ctx, p.Model), fixed), messageTokens(tok))
+	// The stored summary is only replaced once the new one was built
+	if len(pendingSummary(conv, messages, window)) == 0 {
+		return "", s.store.SetSummary(conversationID, "", 0)
+	}
+	return s.updateSummary(ctx, conv, messages, window)
+}
+
+// updateSummary folds messages older than the window that the summary does not cover yet into
+// it, stores the result and returns the summary to use.
+func (s *Service) updateSummary(ctx context.Context, conv *sqlite.Conversation, messages, window []sqlite.Message) (string, error) {
+	pending := pendingSummary(conv, messages, window)
+	if len(pending) == 0 {
+		return conv.Summary, nil
+	}
+	summary, err := s.summarize(ctx, conv.ID, conv.Summary, pending)
+	if err != nil {
+		return conv.Summary, err
+	}
+	through := pending[len(pending)-1].ID
+	if err := s.store.SetSummary(conv.ID, summary, through); err != nil {
+		return conv.Summary, err
+	}
+	conv.Summary, conv.SummaryThrough = summary, through
+	return summary, nil
+}
+
+// pendingSummary returns the messages older than the window that the summary does not cover yet.
+func pendingSummary(conv *sqlite.Conversation, messages, window []sqlite.Message) []sqlite.Message {
+	if len(window) == 0 {
+		return nil
+	}
+	var pending []sqlite.Message
+	for _, m := range messages {
+		if m.ID >= window[0].ID {
+			break
+		}
+		if m.ID > conv.SummaryThrough {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// summarize merges msgs of a conversation into prev, in chunks small enough for the summarizer
+// model.
+func (s *Service) summarize(ctx context.Context, conversationID, prev string, msgs []sqlite.Message) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	model := s.cfg.SummaryModel
+	if model == "" {
+		model = s.currentModel()
+	}
+	tok := ctxutil.ForModel(model)
+	summary := prev
+	for len(msgs) > 0 {
+		var (
+			sb    strings.Builder
+			used  int
+			taken int
+		)
+		for _, m := range msgs {
+			line := roleName(m.Role) + ": " + m.Content + "\n\n"
+			cost := tok.Count(line)
+			if taken > 0 && used+cost > maxSummaryChunkTokens {
+				break
+			}
+			sb.WriteString(line)
+			used += cost
+			taken++
+		}
+		msgs = msgs[taken:]
+
+		current := summary
+		if current == "" {
+			current = "(none yet)"
+		}
+		out, err := s.complete(ctx, conversationID, sqlite.UsageSummary, provider.ChatRequest{
+			Model: model,
+			Messages: []provider.ChatMessage{
+				{Role: "system", Content: summarizerPrompt},
+				{Role: "user", Content: "Current summary:\n" + current + "\n\nNew messages:\n" + sb.String()},
+			},
+			Stream: true,
+		})
+		if err != nil {
+			return "", err
+		}
+		summary = strings.TrimSpace(out)
+	}
+	return summary, nil
+}
+
+func roleName(role string) string {
+	switch role {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	case "system":
+		return "System"
+	}
+	return role
+}
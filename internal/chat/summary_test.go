@@ -0,0 +1,118 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yourname/clichat/internal/config"
+	"github.com/yourname/clichat/internal/memory/sqlite"
+	"github.com/yourname/clichat/internal/provider/litellm"
+	"github.com/yourname/clichat/internal/stream"
+)
+
+// fakeLiteLLM answers every chat completion with the given text and records the requests.
+func fakeLiteLLM(t *testing.T, answer string, reqs *[]litellm.ChatRequest) *litellm.Provider {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req litellm.ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		*reqs = append(*reqs, req)
+		b, _ := json.Marshal(answer)
+		fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%s}}]}\n\ndata: [DONE]\n\n", b)
+	}))
+	t.Cleanup(ts.Close)
+	return litellm.NewProvider(litellm.NewClient(ts.URL, ""), nil)
+}
+
+func TestBuildRequestSummarizesDroppedTurns(t *testing.T) {
+	st, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+	conv, err := st.CreateOrGetConversation("c", "c")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	long := strings.Repeat("x", 400) // ~100 tokens
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		q, _ := st.AppendMessage("c", "user", long)
+		a, _ := st.AppendMessage("c", "assistant", long)
+		ids = append(ids, q, a)
+	}
+	_, _ = st.AppendMessage("c", "user", "latest question")
+
+	var reqs []litellm.ChatRequest
+	cfg := &config.Config{Model: "m", SystemPrompt: "sys", HistoryMaxTokens: 300, AutoSummarize: true, SummaryModel: "cheap"}
+	svc := NewService(cfg, st, fakeLiteLLM(t, "the user asked about x", &reqs), stream.NewRenderer())
+
+	b, err := svc.buildRequest(context.Background(), conv, buildOptions{})
+	if err != nil {
+		t.Fatalf("buildRequest: %v", err)
+	}
+	if len(reqs) != 1 || reqs[0].Model != "cheap" {
+		t.Fatalf("want one summarizer call with the summary model, got %+v", reqs)
+	}
+	// system, summary, last full turn (2 messages), latest question
+	if len(b.req.Messages) != 5 || !strings.HasPrefix(b.req.Messages[1].Content, summaryPreamble) || b.req.Messages[4].Content != "latest question" {
+		t.Fatalf("unexpected request messages: %+v", b.req.Messages)
+	}
+	got, _ := st.GetConversation("c")
+	if got.Summary != "the user asked about x" || got.SummaryThrough != ids[3] {
+		t.Fatalf("summary not stored through #%d: %+v", ids[3], got)
+	}
+	// The summarizer reported no usage, so an estimate is recorded
+	if rows, _ := st.Usage(sqlite.UsageOptions{By: "kind"}); len(rows) != 1 || rows[0].Key != sqlite.UsageSummary || rows[0].PromptTokens == 0 {
+		t.Fatalf("summary usage not recorded: %+v", rows)
+	}
+
+	// A second build with nothing new to fold must not call the summarizer again
+	if _, err := svc.buildRequest(context.Background(), got, buildOptions{}); err != nil {
+		t.Fatalf("buildRequest: %v", err)
+	}
+	if len(reqs) != 1 {
+		t.Fatalf("summarizer called again: %d calls", len(reqs))
+	}
+}
+
+func TestResummarizeKeepsSummaryOnFailure(t *testing.T) {
+	st, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+	if _, err := st.CreateOrGetConversation("c", "c"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	long := strings.Repeat("x", 400)
+	var last int64
+	for i := 0; i < 3; i++ {
+		_, _ = st.AppendMessage("c", "user", long)
+		last, _ = st.AppendMessage("c", "assistant", long)
+	}
+	if err := st.SetSummary("c", "old summary", last); err != nil {
+		t.Fatalf("set summary: %v", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"message":"bad request","type":"invalid_request_error"}}`)
+	}))
+	defer ts.Close()
+	cfg := &config.Config{Model: "m", HistoryMaxTokens: 300, AutoSummarize: true}
+	svc := NewService(cfg, st, litellm.NewProvider(litellm.NewClient(ts.URL, ""), nil), stream.NewRenderer())
+
+	if _, err := svc.Resummarize(context.Background(), "c"); err == nil {
+		t.Fatal("want the provider error")
+	}
+	if got, _ := st.GetConversation("c"); got.Summary != "old summary" || got.SummaryThrough != last {
+		t.Fatalf("summary lost: %+v", got)
+	}
+}
@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/yourname/clichat/internal/memory/sqlite"
+	"github.com/yourname/clichat/internal/provider"
+)
+
+const titlePrompt = "Write a short title (at most 6 words) for a conversation that starts with the exchange below. " +
+	"Reply with the title only: no quotes, no trailing punctuation."
+
+// maxTitleInput caps how much of each message is sent to the titling model.
+const maxTitleInput = 2000
+
+// maxTitleLen caps the stored title length.
+const maxTitleLen = 80
+
+// claimTitle reports whether a title may be requested for a conversation: once per session, so a
+// failing titling model does not cost an extra request on every turn.
+func (s *Service) claimTitle(conversationID string) bool {
+	_, tried := s.titleTried.LoadOrStore(conversationID, true)
+	return !tried
+}
+
+// generateTitle asks the titling model for a short title and stores it unless the user set one.
+// It runs in the background after the first exchange; failures are ignored until the next session.
+func (s *Service) generateTitle(conversationID, userText, assistantText string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	model := s.cfg.TitleModel
+	if model == "" {
+		model = s.currentModel()
+	}
+	req := provider.ChatRequest{
+		Model: model,
+		Messages: []provider.ChatMessage{
+			{Role: "system", Content: titlePrompt},
+			{Role: "user", Content: "User: " + truncate(userText, maxTitleInput) + "\n\nAssistant: " + truncate(assistantText, maxTitleInput)},
+		},
+		Stream: true,
+	}
+	out, err := s.complete(ctx, conversationID, sqlite.UsageTitle, req)
+	if err != nil {
+		return
+	}
+	title := cleanTitle(out)
+	if title == "" {
+		return
+	}
+	_, _ = s.store.SetGeneratedTitle(conversationID, title)
+}
+
+// cleanTitle keeps the first non-empty line of a model answer, stripped of quotes and trailing punctuation.
+func cleanTitle(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "Title:")
+		line = strings.Trim(line, " \t\"'`*#")
+		line = strings.TrimRight(line, ".!:;,")
+		if line == "" {
+			continue
+		}
+		return truncate(line, maxTitleLen)
+	}
+	return ""
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -2,10 +2,13 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,11 +18,22 @@ import (
 	"github.com/yourname/clichat/internal/config"
 	ctxutil "github.com/yourname/clichat/internal/context"
 	"github.com/yourname/clichat/internal/memory/sqlite"
-	"github.com/yourname/clichat/internal/provider/litellm"
+	"github.com/yourname/clichat/internal/persona"
+	"github.com/yourname/clichat/internal/pricing"
+	"github.com/yourname/clichat/internal/provider"
 	"github.com/yourname/clichat/internal/stream"
 )
 
+var (
+	chatConversation string
+	chatContinue     bool
+	chatPersona      string
+)
+
 func init() {
+	chatCmd.Flags().StringVarP(&chatConversation, "conversation", "c", "", "open or create the conversation with this id")
+	chatCmd.Flags().BoolVar(&chatContinue, "continue", false, "reopen the most recently active conversation")
+	chatCmd.Flags().StringVar(&chatPersona, "persona", "", "attach this persona to the conversation (see /persona)")
 	rootCmd.AddCommand(chatCmd)
 }
 
@@ -31,30 +45,89 @@ var chatCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		ctxutil.SetTokenizerDir(cfg.TokenizerDir)
 		store, err := sqlite.Open(cfg.DBPath)
 		if err != nil {
 			return err
 		}
 		defer store.Close()
-		prov := litellm.NewClient(cfg.LiteLLMBaseURL, cfg.LiteLLMAPIKey)
+		prov, err := provider.New(cfg)
+		if err != nil {
+			return err
+		}
 		r := stream.NewRenderer()
 		svc := chat.NewService(cfg, store, prov, r)
+		prices, err := pricing.Load(cfg.PricingFile)
+		if err != nil {
+			return err
+		}
+		svc.SetPricing(prices)
+		personas, err := persona.Load(cfg.PersonasDir)
+		if err != nil {
+			return err
+		}
+		svc.SetPersonas(personas)
+		convID, err := resolveStartConversation(store)
+		if err != nil {
+			return err
+		}
+		conv, err := store.CreateOrGetConversation(convID, convID)
+		if err != nil {
+			return err
+		}
+		if chatPersona != "" {
+			if err := svc.SetPersona(conv.ID, chatPersona); err != nil {
+				return err
+			}
+			conv.Persona = chatPersona
+		}
+		sess := &session{cfg: cfg, store: store, prov: prov, svc: svc, convID: conv.ID}
 
-		fmt.Println("Enter messages (Ctrl+C to quit). Conversation: default")
+		if conv.Persona != "" {
+			fmt.Printf("Enter messages (Ctrl+C to quit). Conversation: %s, persona: %s\n", conversationLabel(conv), conv.Persona)
+		} else {
+			fmt.Printf("Enter messages (Ctrl+C to quit). Conversation: %s\n", conversationLabel(conv))
+		}
 
 		ln := liner.NewLiner()
 		defer ln.Close()
 		ln.SetCtrlCAborts(true)
+		sess.ln = ln
 
 		ln.SetCompleter(func(line string) (c []string) {
 			trim := strings.TrimSpace(line)
 			lower := strings.ToLower(trim)
 
 			// Base command suggestions when user starts typing '/'
-			allCmds := []string{"/models", "/model ", "/history", "/clear", "/contextwindow"}
+			allCmds := []string{"/models", "/model ", "/history", "/clear", "/contextwindow", "/conversations", "/new ", "/switch ", "/rename ", "/delete ", "/search ", "/fork ", "/retry", "/edit", "/variants", "/pin", "/unpin ", "/summary", "/resummarize", "/cost", "/recall ", "/context", "/persona "}
 			if cfg.AllowLocalShell {
 				allCmds = append(allCmds, "/bash ")
 			}
+			if partial, ok := strings.CutPrefix(lower, "/persona "); ok {
+				for _, name := range append(svc.Personas().Names(), "off") {
+					if strings.HasPrefix(name, partial) {
+						c = append(c, "/persona "+name)
+					}
+				}
+				return c
+			}
+			// Conversation completion for '/switch <partial>' and '/delete <partial>'
+			for _, prefix := range []string{"/switch ", "/delete "} {
+				if strings.HasPrefix(lower, prefix) {
+					partial := strings.TrimSpace(trim[len(prefix):])
+					convs, err := store.ListConversations()
+					if err != nil {
+						return nil
+					}
+					for _, cv := range convs {
+						if strings.HasPrefix(cv.ID, partial) {
+							c = append(c, prefix+cv.ID)
+						}
+					}
+					return c
+				}
+			}
+
 			if strings.HasPrefix(lower, "/") && !strings.HasPrefix(lower, "/model") {
 				for _, cmd := range allCmds {
 					if strings.HasPrefix(cmd, lower) || lower == "/" {
@@ -92,7 +165,7 @@ var chatCmd = &cobra.Command{
 
 		for {
 			// Plain user prompt (avoid ANSI here to prevent liner errors on Windows)
-			line, err := ln.Prompt("you> ")
+			line, err := ln.Prompt("you@" + sess.convID + "> ")
 			if err != nil {
 				if err == liner.ErrPromptAborted {
 					fmt.Println()
@@ -107,8 +180,8 @@ var chatCmd = &cobra.Command{
 			ln.AppendHistory(line)
 
 			if strings.HasPrefix(line, "/") {
-				if handled, err := handleSlashCommand(context.Background(), prov, cfg, line); err != nil {
-					fmt.Println("error:", err)
+				if handled, err := handleSlashCommand(context.Background(), sess, line); err != nil {
+					printError(os.Stdout, err)
 					continue
 				} else if handled {
 					continue
@@ -116,9 +189,22 @@ var chatCmd = &cobra.Command{
 			}
 
 			// Blue tag for the model name; streaming stays blue; service resets color at end
-			fmt.Printf("\x1b[34m%s> ", currentModelPrompt(cfg))
-			if err := svc.HandleUserInput(context.Background(), "default", line); err != nil {
-				fmt.Println("\nerror:", err)
+			fmt.Printf("\x1b[34m%s> ", sess.modelPrompt())
+			err = svc.HandleUserInput(context.Background(), sess.convID, line)
+			var overflow *chat.OverflowError
+			if errors.As(err, &overflow) && !overflow.Trimmed {
+				// Refused before sending: let the user decide whether to drop older history
+				fmt.Println("\x1b[0m\n" + err.Error())
+				if answer, _ := ln.Prompt("trim older history and send anyway? [y/N] "); strings.EqualFold(strings.TrimSpace(answer), "y") {
+					fmt.Printf("\x1b[34m%s> ", sess.modelPrompt())
+					err = svc.SendWithOverflow(context.Background(), sess.convID, line, config.OverflowTrim)
+				} else {
+					err = nil
+				}
+			}
+			if err != nil {
+				fmt.Println()
+				printError(os.Stdout, err)
 			}
 			fmt.Println()
 		}
@@ -126,6 +212,34 @@ var chatCmd = &cobra.Command{
 	},
 }
 
+// resolveStartConversation picks the conversation from --conversation/--continue, defaulting to "default".
+func resolveStartConversation(store *sqlite.Store) (string, error) {
+	switch {
+	case chatConversation != "" && chatContinue:
+		return "", errors.New("--conversation and --continue are mutually exclusive")
+	case chatConversation != "":
+		return chatConversation, nil
+	case chatContinue:
+		conv, err := store.MostRecentConversation()
+		if errors.Is(err, sqlite.ErrConversationNotFound) {
+			return defaultConversationID, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return conv.ID, nil
+	}
+	return defaultConversationID, nil
+}
+
+// conversationLabel renders a conversation as "id" or "id (title)" when a distinct title is set.
+func conversationLabel(c *sqlite.Conversation) string {
+	if c.Title == "" || c.Title == c.ID {
+		return c.ID
+	}
+	return fmt.Sprintf("%s (%s)", c.ID, c.Title)
+}
+
 func currentModelPrompt(cfg *config.Config) string {
 	name := cfg.Model
 	if st, err := config.LoadState(); err == nil && st.Model != "" {
@@ -137,7 +251,39 @@ func currentModelPrompt(cfg *config.Config) string {
 	return name
 }
 
-func handleSlashCommand(ctx context.Context, prov *litellm.Client, cfg *config.Config, line string) (bool, error) {
+// modelPrompt is the label of the model answering in the current conversation, which may be
+// its persona's model rather than the active one.
+func (sess *session) modelPrompt() string {
+	if name := sess.svc.ConversationModel(sess.convID); name != "" {
+		return name
+	}
+	return "assistant"
+}
+
+// parseMessageID accepts "12" or "#12" as shown by /history.
+func parseMessageID(s string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(s, "#"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid message id %q (see /history)", s)
+	}
+	return id, nil
+}
+
+// defaultConversationID is the conversation a chat session starts in.
+const defaultConversationID = "default"
+
+// session holds the state shared by the interactive loop and its slash commands.
+type session struct {
+	cfg    *config.Config
+	store  *sqlite.Store
+	prov   provider.Provider
+	svc    *chat.Service
+	ln     *liner.State
+	convID string
+}
+
+func handleSlashCommand(ctx context.Context, sess *session, line string) (bool, error) {
+	cfg, store, prov := sess.cfg, sess.store, sess.prov
 	parts := strings.Fields(line)
 	if len(parts) == 0 {
 		return false, nil
@@ -148,13 +294,7 @@ func handleSlashCommand(ctx context.Context, prov *litellm.Client, cfg *config.C
 		if err != nil {
 			return true, err
 		}
-		for _, m := range mods {
-			name := m.Name
-			if name == "" {
-				name = m.ID
-			}
-			fmt.Println(name)
-		}
+		printModels(os.Stdout, mods)
 		return true, nil
 	case "/model":
 		if len(parts) < 2 {
@@ -174,15 +314,17 @@ func handleSlashCommand(ctx context.Context, prov *litellm.Client, cfg *config.C
 		if err := config.SaveState(st); err != nil {
 			return true, err
 		}
-		fmt.Println("default model set to:", name)
+		if info, ok := sess.svc.ModelInfo(ctx, name); ok && info.MaxInputTokens > 0 {
+			fmt.Printf("default model set to: %s (context window %d tokens)\n", name, info.MaxInputTokens)
+		} else {
+			fmt.Println("default model set to:", name)
+		}
+		if model := sess.modelPrompt(); model != name {
+			fmt.Printf("this conversation's persona answers with %s\n", model)
+		}
 		return true, nil
 	case "/history":
-		store, err := sqlite.Open(cfg.DBPath)
-		if err != nil {
-			return true, err
-		}
-		defer store.Close()
-		msgs, err := store.ListMessages("default", 200)
+		msgs, err := store.ListMessages(sess.convID, 200)
 		if err != nil {
 			return true, err
 		}
@@ -192,35 +334,371 @@ func handleSlashCommand(ctx context.Context, prov *litellm.Client, cfg *config.C
 			case "user":
 				role = "you"
 			case "assistant":
-				role = currentModelPrompt(cfg)
+				role = m.Model
+				if role == "" {
+					role = currentModelPrompt(cfg)
+				}
 			}
-			fmt.Printf("%s> %s\n", role, strings.TrimSpace(m.Content))
+			pin := ""
+			if m.Pinned {
+				pin = " (pinned)"
+			}
+			fmt.Printf("[#%d]%s %s> %s\n", m.ID, pin, role, strings.TrimSpace(m.Content))
 		}
 		return true, nil
 	case "/clear":
-		store, err := sqlite.Open(cfg.DBPath)
+		if err := store.ClearConversation(sess.convID); err != nil {
+			return true, err
+		}
+		fmt.Println("history cleared for conversation:", sess.convID)
+		return true, nil
+	case "/conversations":
+		convs, err := store.ListConversations()
 		if err != nil {
 			return true, err
 		}
-		defer store.Close()
-		if err := store.ClearConversation("default"); err != nil {
+		printConversations(os.Stdout, convs, sess.convID)
+		return true, nil
+	case "/new":
+		id, err := newConversationID(store)
+		if err != nil {
+			return true, err
+		}
+		if len(parts) > 1 {
+			id = parts[1]
+		}
+		if _, err := store.GetConversation(id); err == nil {
+			return true, fmt.Errorf("conversation %q already exists (use /switch %s)", id, id)
+		} else if !errors.Is(err, sqlite.ErrConversationNotFound) {
+			return true, err
+		}
+		if _, err := store.CreateOrGetConversation(id, id); err != nil {
 			return true, err
 		}
-		fmt.Println("history cleared for conversation: default")
+		sess.convID = id
+		fmt.Println("switched to new conversation:", id)
 		return true, nil
-	case "/contextwindow":
-		store, err := sqlite.Open(cfg.DBPath)
+	case "/switch":
+		if len(parts) < 2 {
+			fmt.Println("usage: /switch <conversation-id>")
+			return true, nil
+		}
+		conv, err := store.GetConversation(parts[1])
 		if err != nil {
 			return true, err
 		}
-		defer store.Close()
-		conv, err := store.CreateOrGetConversation("default", "default")
+		sess.convID = conv.ID
+		fmt.Printf("switched to conversation: %s (%d messages)\n", conversationLabel(conv), conv.MessageCount)
+		return true, nil
+	case "/rename":
+		title := strings.TrimSpace(strings.TrimPrefix(line, parts[0]))
+		if title == "" {
+			fmt.Println("usage: /rename <title>")
+			return true, nil
+		}
+		if _, err := store.CreateOrGetConversation(sess.convID, sess.convID); err != nil {
+			return true, err
+		}
+		if err := store.RenameConversation(sess.convID, title); err != nil {
+			return true, err
+		}
+		fmt.Printf("conversation %s renamed to: %s\n", sess.convID, title)
+		return true, nil
+	case "/delete":
+		if len(parts) < 2 {
+			fmt.Println("usage: /delete <conversation-id>")
+			return true, nil
+		}
+		id := parts[1]
+		if err := store.DeleteConversation(id); err != nil {
+			return true, err
+		}
+		fmt.Println("deleted conversation:", id)
+		if id == sess.convID {
+			sess.convID = defaultConversationID
+			fmt.Println("switched to conversation:", sess.convID)
+		}
+		return true, nil
+	case "/fork":
+		if len(parts) < 2 {
+			fmt.Println("usage: /fork <message-id> [new-conversation-id]")
+			return true, nil
+		}
+		msgID, err := parseMessageID(parts[1])
 		if err != nil {
 			return true, err
 		}
+		id, err := newConversationID(store)
+		if err != nil {
+			return true, err
+		}
+		if len(parts) > 2 {
+			id = parts[2]
+		}
+		conv, err := store.ForkConversation(id, msgID)
+		if err != nil {
+			return true, err
+		}
+		sess.convID = conv.ID
+		fmt.Printf("forked %s at #%d into %s (%d messages); switched\n", conv.ForkedFrom, msgID, conv.ID, conv.MessageCount)
+		return true, nil
+	case "/retry":
+		model := ""
+		if len(parts) > 1 {
+			model = parts[1]
+		}
+		label := model
+		if label == "" {
+			label = sess.modelPrompt()
+		}
+		fmt.Printf("\x1b[34m%s> ", label)
+		if err := sess.svc.Retry(ctx, sess.convID, model); err != nil {
+			fmt.Println()
+			return true, err
+		}
+		fmt.Println()
+		return true, nil
+	case "/edit":
+		text := strings.TrimSpace(strings.TrimPrefix(line, parts[0]))
+		if text == "" {
+			last, err := sess.svc.LastUserMessage(sess.convID)
+			if err != nil {
+				return true, err
+			}
+			// Prefill the prompt with the previous text so it can be revised in place
+			text, err = sess.ln.PromptWithSuggestion("edit> ", last.Content, -1)
+			if err != nil {
+				if err == liner.ErrPromptAborted {
+					fmt.Println()
+					return true, nil
+				}
+				return true, err
+			}
+			text = strings.TrimSpace(text)
+			if text == "" || text == last.Content {
+				fmt.Println("edit cancelled")
+				return true, nil
+			}
+		}
+		fmt.Printf("\x1b[34m%s> ", sess.modelPrompt())
+		if err := sess.svc.Edit(ctx, sess.convID, text); err != nil {
+			fmt.Println()
+			return true, err
+		}
+		fmt.Println()
+		return true, nil
+	case "/variants":
+		var msgID int64
+		if len(parts) > 1 {
+			id, err := parseMessageID(parts[1])
+			if err != nil {
+				return true, err
+			}
+			msgID = id
+		} else {
+			msgs, err := store.AllMessages(sess.convID)
+			if err != nil {
+				return true, err
+			}
+			for i := len(msgs) - 1; i >= 0; i-- {
+				if msgs[i].Role == "assistant" {
+					msgID = msgs[i].ID
+					break
+				}
+			}
+			if msgID == 0 {
+				fmt.Println("no answers in this conversation yet")
+				return true, nil
+			}
+		}
+		variants, err := store.ListVariants(msgID)
+		if err != nil {
+			return true, err
+		}
+		for i, v := range variants {
+			state := "current"
+			if v.Superseded {
+				state = "superseded"
+			}
+			label := v.Role
+			if v.Model != "" {
+				label += " " + v.Model
+			}
+			fmt.Printf("--- variant %d/%d [#%d] %s (%s)\n%s\n", i+1, len(variants), v.ID, label, state, strings.TrimSpace(v.Content))
+		}
+		return true, nil
+	case "/pin", "/unpin":
+		if len(parts) < 2 {
+			if parts[0] == "/unpin" {
+				fmt.Println("usage: /unpin <message-id>")
+				return true, nil
+			}
+			pinned, err := store.ListPinned(sess.convID)
+			if err != nil {
+				return true, err
+			}
+			if len(pinned) == 0 {
+				fmt.Println("no pinned messages (usage: /pin <message-id>)")
+				return true, nil
+			}
+			for _, m := range pinned {
+				fmt.Printf("[#%d] %s> %.80s\n", m.ID, m.Role, strings.Join(strings.Fields(m.Content), " "))
+			}
+			return true, nil
+		}
+		msgID, err := parseMessageID(parts[1])
+		if err != nil {
+			return true, err
+		}
+		m, err := store.GetMessage(msgID)
+		if err != nil {
+			return true, err
+		}
+		if m.ConversationID != sess.convID {
+			return true, fmt.Errorf("message #%d belongs to conversation %s", msgID, m.ConversationID)
+		}
+		pin := parts[0] == "/pin"
+		if err := store.SetPinned(msgID, pin); err != nil {
+			return true, err
+		}
+		if pin {
+			fmt.Printf("pinned #%d; it will always be sent as context\n", msgID)
+		} else {
+			fmt.Printf("unpinned #%d\n", msgID)
+		}
+		return true, nil
+	case "/summary":
+		conv, err := store.CreateOrGetConversation(sess.convID, sess.convID)
+		if err != nil {
+			return true, err
+		}
+		if conv.Summary == "" {
+			fmt.Println("no summary yet: the whole conversation still fits in the context window")
+			return true, nil
+		}
+		tok := ctxutil.ForModel(sess.modelPrompt())
+		fmt.Printf("summary through #%d (%d tokens):\n%s\n", conv.SummaryThrough, tok.Count(conv.Summary), conv.Summary)
+		return true, nil
+	case "/resummarize":
+		if _, err := store.CreateOrGetConversation(sess.convID, sess.convID); err != nil {
+			return true, err
+		}
+		summary, err := sess.svc.Resummarize(ctx, sess.convID)
+		if err != nil {
+			return true, err
+		}
+		if summary == "" {
+			fmt.Println("summary cleared: the whole conversation fits in the context window")
+			return true, nil
+		}
+		fmt.Println(summary)
+		return true, nil
+	case "/cost":
+		rows, err := store.Usage(sqlite.UsageOptions{ConversationID: sess.convID, By: "model"})
+		if err != nil {
+			return true, err
+		}
+		printUsage(os.Stdout, "model", rows)
+		return true, nil
+	case "/context":
+		arg := strings.TrimSpace(strings.TrimPrefix(line, parts[0]))
+		asJSON := false
+		if rest, ok := strings.CutPrefix(arg, "--json"); ok && (rest == "" || rest[0] == ' ') {
+			asJSON, arg = true, strings.TrimSpace(rest)
+		}
+		rep, err := sess.svc.InspectContext(ctx, sess.convID, arg)
+		if err != nil {
+			return true, err
+		}
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return true, enc.Encode(rep)
+		}
+		printContextReport(os.Stdout, rep)
+		return true, nil
+	case "/recall":
+		arg := strings.TrimSpace(strings.TrimPrefix(line, parts[0]))
+		switch arg {
+		case "", "on", "off":
+			if cfg.EmbeddingModel == "" {
+				fmt.Println(chat.ErrRecallDisabled)
+				return true, nil
+			}
+			conv, err := store.CreateOrGetConversation(sess.convID, sess.convID)
+			if err != nil {
+				return true, err
+			}
+			if arg != "" {
+				if err := store.SetRecall(sess.convID, arg == "on"); err != nil {
+					return true, err
+				}
+				conv.Recall = arg == "on"
+			}
+			state := "off"
+			if conv.Recall {
+				state = "on"
+			}
+			fmt.Printf("recall is %s for this conversation (embeddings: %s)\n", state, cfg.EmbeddingModel)
+			return true, nil
+		}
+		hits, err := sess.svc.Recall(ctx, sess.convID, arg)
+		if err != nil {
+			return true, err
+		}
+		printMemoryHits(os.Stdout, hits)
+		return true, nil
+	case "/persona":
+		arg := strings.TrimSpace(strings.TrimPrefix(line, parts[0]))
+		switch arg {
+		case "":
+			conv, err := store.CreateOrGetConversation(sess.convID, sess.convID)
+			if err != nil {
+				return true, err
+			}
+			if conv.Persona == "" {
+				fmt.Println("no persona (using SYSTEM_PROMPT)")
+			} else {
+				fmt.Println("persona:", conv.Persona)
+			}
+			printPersonas(os.Stdout, sess.svc.Personas(), cfg.PersonasDir)
+			return true, nil
+		case "off", "none":
+			arg = ""
+		}
+		if _, err := store.CreateOrGetConversation(sess.convID, sess.convID); err != nil {
+			return true, err
+		}
+		if err := sess.svc.SetPersona(sess.convID, arg); err != nil {
+			return true, err
+		}
+		if arg == "" {
+			fmt.Println("persona detached")
+		} else {
+			fmt.Printf("persona set to: %s (model %s)\n", arg, sess.modelPrompt())
+		}
+		return true, nil
+	case "/search":
+		query := strings.TrimSpace(strings.TrimPrefix(line, parts[0]))
+		if query == "" {
+			fmt.Println("usage: /search <query>")
+			return true, nil
+		}
+		hits, err := store.Search(query, "", 20)
+		if err != nil {
+			return true, err
+		}
+		printSearchHits(os.Stdout, hits)
+		return true, nil
+	case "/contextwindow":
+		conv, err := store.CreateOrGetConversation(sess.convID, sess.convID)
+		if err != nil {
+			return true, err
+		}
+		tok := ctxutil.ForModel(sess.modelPrompt())
 		used := conv.ContextPromptTokens + conv.ContextAnswerTokens
 		if used == 0 {
-			msgs, err := store.ListMessages("default", 200)
+			msgs, err := store.ListMessages(sess.convID, 200)
 			if err == nil {
 				answerTokens := 0
 				promptTokens := 0
@@ -239,25 +717,41 @@ func handleSlashCommand(ctx context.Context, prov *litellm.Client, cfg *config.C
 						if m.Role == "assistant" {
 							answerCount++
 							if i == idx {
-								answerTokens += ctxutil.EstimateTokens(m.Content)
+								answerTokens += tok.Count(m.Content)
 							}
 							continue
 						}
 						// user/system as prompt
 						promptCount++
-						promptTokens += ctxutil.EstimateTokens(m.Content)
+						promptTokens += tok.Count(m.Content)
+					}
+					// Prefer the usage recorded with the last answer over estimates
+					if idx >= 0 && msgs[idx].TotalTokens > 0 {
+						promptTokens, answerTokens = msgs[idx].PromptTokens, msgs[idx].CompletionTokens
 					}
 				}
-				_ = store.UpdateContextStats("default", promptTokens, answerTokens, promptCount, answerCount)
-				conv, _ = store.CreateOrGetConversation("default", "default")
+				_ = store.UpdateContextStats(sess.convID, promptTokens, answerTokens, promptCount, answerCount)
+				conv, _ = store.CreateOrGetConversation(sess.convID, sess.convID)
 				used = conv.ContextPromptTokens + conv.ContextAnswerTokens
 			}
 		}
-		if cfg.ModelContextTokens > 0 {
-			fmt.Printf("context: prompts=%d, answers=%d, tokens %d/%d (%s)\n", conv.PromptMessageCount, conv.AnswerMessageCount, used, cfg.ModelContextTokens, ctxutil.PercentUsed(used, cfg.ModelContextTokens))
+		limit := sess.svc.ContextTokens(ctx, sess.modelPrompt())
+		if limit > 0 {
+			fmt.Printf("context: prompts=%d, answers=%d, tokens %d/%d (%s)\n", conv.PromptMessageCount, conv.AnswerMessageCount, used, limit, ctxutil.PercentUsed(used, limit))
 		} else {
 			fmt.Printf("context: prompts=%d, answers=%d, tokens %d (N/A)\n", conv.PromptMessageCount, conv.AnswerMessageCount, used)
 		}
+		if pinned, err := store.ListPinned(sess.convID); err == nil && len(pinned) > 0 {
+			pinnedTokens := 0
+			for _, m := range pinned {
+				pinnedTokens += tok.Count(m.Content)
+			}
+			if limit > 0 {
+				fmt.Printf("pinned: %d messages, tokens %d (%s)\n", len(pinned), pinnedTokens, ctxutil.PercentUsed(pinnedTokens, limit))
+			} else {
+				fmt.Printf("pinned: %d messages, tokens %d\n", len(pinned), pinnedTokens)
+			}
+		}
 		return true, nil
 	case "/bash":
 		if !cfg.AllowLocalShell {
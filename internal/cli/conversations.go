@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yourname/clichat/internal/config"
+	"github.com/yourname/clichat/internal/memory/sqlite"
+)
+
+func init() {
+	conversationsCmd.AddCommand(conversationsLsCmd)
+	conversationsCmd.AddCommand(conversationsRmCmd)
+	conversationsCmd.AddCommand(conversationsRenameCmd)
+	rootCmd.AddCommand(conversationsCmd)
+}
+
+var conversationsCmd = &cobra.Command{
+	Use:     "conversations",
+	Aliases: []string{"convs"},
+	Short:   "Manage stored conversations",
+	RunE:    conversationsLsCmd.RunE,
+}
+
+var conversationsLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List conversations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withStore(func(store *sqlite.Store) error {
+			convs, err := store.ListConversations()
+			if err != nil {
+				return err
+			}
+			printConversations(os.Stdout, convs, "")
+			return nil
+		})
+	},
+}
+
+var conversationsRmCmd = &cobra.Command{
+	Use:   "rm <id>...",
+	Short: "Delete conversations and their messages",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withStore(func(store *sqlite.Store) error {
+			for _, id := range args {
+				if err := store.DeleteConversation(id); err != nil {
+					return fmt.Errorf("%s: %w", id, err)
+				}
+				fmt.Println("deleted conversation:", id)
+			}
+			return nil
+		})
+	},
+}
+
+var conversationsRenameCmd = &cobra.Command{
+	Use:   "rename <id> <title>",
+	Short: "Set the title of a conversation",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		title := strings.TrimSpace(strings.Join(args[1:], " "))
+		if title == "" {
+			return fmt.Errorf("title required")
+		}
+		return withStore(func(store *sqlite.Store) error {
+			if err := store.RenameConversation(args[0], title); err != nil {
+				return err
+			}
+			fmt.Printf("conversation %s renamed to: %s\n", args[0], title)
+			return nil
+		})
+	},
+}
+
+// withStore loads config, opens the sqlite store and closes it after fn returns.
+func withStore(fn func(store *sqlite.Store) error) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	store, err := sqlite.Open(cfg.DBPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	return fn(store)
+}
+
+// printConversations writes a table of conversations, marking current with '*'.
+func printConversations(w io.Writer, convs []sqlite.Conversation, current string) {
+	if len(convs) == 0 {
+		fmt.Fprintln(w, "no conversations")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ID\tTITLE\tMESSAGES\tCREATED\tLAST ACTIVE\tFORKED FROM")
+	for _, c := range convs {
+		mark := " "
+		if c.ID == current {
+			mark = "*"
+		}
+		fork := ""
+		if c.ForkedFrom != "" {
+			fork = fmt.Sprintf("%s#%d", c.ForkedFrom, c.ForkedFromMessage)
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\t%d\t%s\t%s\t%s\n", mark, c.ID, c.Title, c.MessageCount, formatTime(c.CreatedAt), formatTime(c.LastActiveAt), fork)
+	}
+	_ = tw.Flush()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// newConversationID returns a free timestamp-based id for conversations created without a
+// name, with a counter appended when several are created within the same second.
+func newConversationID(store *sqlite.Store) (string, error) {
+	base := time.Now().Format("20060102-150405")
+	id := base
+	for n := 2; ; n++ {
+		_, err := store.GetConversation(id)
+		if errors.Is(err, sqlite.ErrConversationNotFound) {
+			return id, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+}
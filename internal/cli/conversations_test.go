@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/yourname/clichat/internal/memory/sqlite"
+)
+
+func TestNewConversationIDIsFree(t *testing.T) {
+	st, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+	seen := map[string]bool{}
+	// Well within one second, so only the counter keeps them apart
+	for i := 0; i < 3; i++ {
+		id, err := newConversationID(st)
+		if err != nil {
+			t.Fatalf("newConversationID: %v", err)
+		}
+		if seen[id] {
+			t.Fatalf("id %s handed out twice", id)
+		}
+		seen[id] = true
+		if _, err := st.CreateOrGetConversation(id, id); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/yourname/clichat/internal/provider"
+)
+
+// maxErrorDetail caps how much of a provider's own message is shown.
+const maxErrorDetail = 200
+
+// printError prints err, explaining provider errors with what to do about them.
+func printError(w io.Writer, err error) {
+	msg, hint := explainError(err)
+	fmt.Fprintln(w, "error:", msg)
+	if hint != "" {
+		fmt.Fprintln(w, "  "+hint)
+	}
+}
+
+// explainError returns a concise description of err and a suggested action, if there is one.
+func explainError(err error) (msg, hint string) {
+	var (
+		auth      *provider.AuthError
+		rateLimit *provider.RateLimitError
+		tooLong   *provider.ContextLengthError
+		notFound  *provider.ModelNotFoundError
+		filtered  *provider.ContentFilterError
+		server    *provider.ServerError
+		stall     *provider.StallError
+		api       *provider.APIError
+	)
+	switch {
+	case errors.As(err, &auth):
+		msg, hint = "the provider rejected the API key", "check "+apiKeyVar(auth.Provider)+" in .env"
+	case errors.As(err, &rateLimit):
+		msg, hint = "rate limited by the provider", "wait a moment, then /retry"
+		if rateLimit.RetryAfter > 0 {
+			hint = fmt.Sprintf("wait %s, then /retry", rateLimit.RetryAfter)
+		}
+	case errors.As(err, &tooLong):
+		msg = "the conversation is too long for the model's context window"
+		hint = "start over with /clear, switch to a model with a larger window with /model, or lower HISTORY_MAX_TOKENS"
+	case errors.As(err, &notFound):
+		msg = "the model is not available"
+		if notFound.Model != "" {
+			msg = fmt.Sprintf("model %q is not available", notFound.Model)
+		}
+		hint = "run /models to list the available models, then /model <name>"
+	case errors.As(err, &filtered):
+		msg, hint = "the provider's content filter blocked the request", "rephrase the prompt and try again"
+	case errors.As(err, &server):
+		msg, hint = "the provider failed or is overloaded", "try again shortly with /retry"
+	case errors.As(err, &stall):
+		return err.Error(), "/retry, or raise LLM_FIRST_TOKEN_TIMEOUT and LLM_IDLE_TIMEOUT for slow models"
+	default:
+		return err.Error(), ""
+	}
+	if errors.As(err, &api) && api.Message != "" {
+		msg += " (" + api.Provider + ": " + truncate(api.Message, maxErrorDetail) + ")"
+	}
+	return msg, hint
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
+
+// apiKeyVar names the setting holding the API key of the named provider.
+func apiKeyVar(name string) string {
+	switch name {
+	case "litellm":
+		return "LITELLM_API_KEY"
+	case "anthropic":
+		return "ANTHROPIC_API_KEY"
+	}
+	return "the provider's API key"
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourname/clichat/internal/provider"
+)
+
+func TestExplainError(t *testing.T) {
+	api := func(name, msg string) *provider.APIError {
+		return &provider.APIError{Provider: name, StatusCode: 400, Message: msg}
+	}
+	cases := []struct {
+		name      string
+		err       error
+		msg, hint string
+	}{
+		{"auth", &provider.AuthError{APIError: api("litellm", "Invalid proxy server token passed")},
+			"the provider rejected the API key (litellm: Invalid proxy server token passed)", "check LITELLM_API_KEY in .env"},
+		{"auth without key setting", &provider.AuthError{APIError: api("ollama", "forbidden")},
+			"the provider rejected the API key (ollama: forbidden)", "check the provider's API key in .env"},
+		{"rate limit", &provider.RateLimitError{APIError: api("anthropic", "slow down")},
+			"rate limited by the provider (anthropic: slow down)", "wait a moment, then /retry"},
+		{"rate limit with retry-after", &provider.RateLimitError{APIError: api("anthropic", "slow down"), RetryAfter: 30 * time.Second},
+			"rate limited by the provider (anthropic: slow down)", "wait 30s, then /retry"},
+		{"context length", &provider.ContextLengthError{APIError: api("litellm", "too long")},
+			"the conversation is too long for the model's context window (litellm: too long)",
+			"start over with /clear, switch to a model with a larger window with /model, or lower HISTORY_MAX_TOKENS"},
+		{"model not found", &provider.ModelNotFoundError{APIError: api("litellm", "Invalid model name"), Model: "gpt-9"},
+			`model "gpt-9" is not available (litellm: Invalid model name)`, "run /models to list the available models, then /model <name>"},
+		{"model not found without name", &provider.ModelNotFoundError{APIError: api("ollama", "not found")},
+			"the model is not available (ollama: not found)", "run /models to list the available models, then /model <name>"},
+		{"content filter", &provider.ContentFilterError{APIError: api("litellm", "filtered")},
+			"the provider's content filter blocked the request (litellm: filtered)", "rephrase the prompt and try again"},
+		{"server", &provider.ServerError{APIError: api("anthropic", "Overloaded")},
+			"the provider failed or is overloaded (anthropic: Overloaded)", "try again shortly with /retry"},
+		{"wrapped", fmt.Errorf("list models: %w", &provider.ServerError{APIError: api("litellm", "")}),
+			"the provider failed or is overloaded", "try again shortly with /retry"},
+		{"stall", &provider.StallError{FirstToken: true, After: 2 * time.Minute},
+			"no response from the provider within 2m0s", "/retry, or raise LLM_FIRST_TOKEN_TIMEOUT and LLM_IDLE_TIMEOUT for slow models"},
+		{"untyped api error", api("litellm", "odd"), "litellm: 400 Bad Request: odd", ""},
+		{"other", errors.New("boom"), "boom", ""},
+	}
+	for _, c := range cases {
+		msg, hint := explainError(c.err)
+		if msg != c.msg || hint != c.hint {
+			t.Errorf("%s:\n got %q, %q\nwant %q, %q", c.name, msg, hint, c.msg, c.hint)
+		}
+	}
+}
+
+func TestExplainErrorTruncatesOnRunes(t *testing.T) {
+	long := strings.Repeat("é", maxErrorDetail+10)
+	msg, _ := explainError(&provider.ServerError{APIError: &provider.APIError{Provider: "p", Message: long}})
+	want := "the provider failed or is overloaded (p: " + strings.Repeat("é", maxErrorDetail) + "…)"
+	if msg != want {
+		t.Fatalf("got %q", msg)
+	}
+}
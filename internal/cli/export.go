@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yourname/clichat/internal/export"
+	"github.com/yourname/clichat/internal/memory/sqlite"
+)
+
+var (
+	exportConversation string
+	exportAll          bool
+	exportFormat       string
+	exportOutput       string
+)
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportConversation, "conversation", "c", "", "conversation id to export")
+	exportCmd.Flags().BoolVar(&exportAll, "all", false, "export all conversations")
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "", "output format: "+strings.Join(export.Formats, "|")+" (default: from -o extension, else md)")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "-", "output file ('-' for stdout)")
+	rootCmd.AddCommand(exportCmd)
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export conversations to Markdown, JSON, JSONL or HTML",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if exportAll == (exportConversation != "") {
+			return errors.New("specify exactly one of --conversation <id> or --all")
+		}
+		format := exportFormat
+		if format == "" {
+			format = export.FormatFromPath(exportOutput)
+		}
+		if format == "" {
+			format = export.FormatMarkdown
+		}
+		return withStore(func(store *sqlite.Store) error {
+			var convs []sqlite.Conversation
+			if exportAll {
+				all, err := store.ListConversations()
+				if err != nil {
+					return err
+				}
+				convs = all
+			} else {
+				c, err := store.GetConversation(exportConversation)
+				if err != nil {
+					return fmt.Errorf("%s: %w", exportConversation, err)
+				}
+				convs = []sqlite.Conversation{*c}
+			}
+			out := make([]export.Conversation, 0, len(convs))
+			for i := range convs {
+				msgs, err := store.AllMessages(convs[i].ID)
+				if err != nil {
+					return err
+				}
+				out = append(out, export.FromStore(&convs[i], msgs))
+			}
+			return writeOutput(exportOutput, func(w io.Writer) error {
+				return export.Write(w, format, out)
+			})
+		})
+	},
+}
+
+// writeOutput runs fn against stdout for "-" or "", otherwise against a newly created file.
+func writeOutput(path string, fn func(w io.Writer) error) error {
+	if path == "" || path == "-" {
+		return fn(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yourname/clichat/internal/importer"
+	"github.com/yourname/clichat/internal/memory/sqlite"
+)
+
+var importFormat string
+
+func init() {
+	importCmd.Flags().StringVarP(&importFormat, "format", "f", importer.FormatChatGPT, "input format: "+strings.Join(importer.Formats, "|"))
+	rootCmd.AddCommand(importCmd)
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import conversations from a ChatGPT export or OpenAI-style JSONL",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		convs, err := importer.Parse(f, importFormat)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", args[0], err)
+		}
+		return withStore(func(store *sqlite.Store) error {
+			res, err := importer.Load(store, convs)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("imported %d conversations, %d messages (%d duplicates skipped)\n", res.Conversations, res.Messages, res.SkippedDuplicates)
+			for _, from := range slices.Sorted(maps.Keys(res.Renamed)) {
+				fmt.Printf("  %s already exists, imported as %s\n", from, res.Renamed[from])
+			}
+			return nil
+		})
+	},
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/yourname/clichat/internal/chat"
+	ctxutil "github.com/yourname/clichat/internal/context"
+)
+
+// printContextReport writes the request of a /context dry run: model, sampling parameters,
+// tools and every message with its token count and an excerpt.
+func printContextReport(w io.Writer, rep *chat.ContextReport) {
+	req := rep.Request
+	fmt.Fprintf(w, "model: %s (tokenizer: %s)\n", req.Model, rep.Tokenizer)
+	sampling := "not sent"
+	if req.Temperature != nil || req.TopP != nil {
+		var params []string
+		if req.Temperature != nil {
+			params = append(params, fmt.Sprintf("temperature=%g", *req.Temperature))
+		}
+		if req.TopP != nil {
+			params = append(params, fmt.Sprintf("top_p=%g", *req.TopP))
+		}
+		sampling = strings.Join(params, " ")
+	}
+	fmt.Fprintf(w, "sampling: %s\n", sampling)
+	tools := "none"
+	if len(req.Tools) > 0 {
+		var names []string
+		for _, t := range req.Tools {
+			names = append(names, t.Type)
+		}
+		tools = strings.Join(names, ", ")
+	}
+	fmt.Fprintf(w, "tools: %s\n\n", tools)
+
+	oneLine := strings.NewReplacer("\n", "⏎", "\r", "", "\t", " ")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tROLE\tTOKENS\tCONTENT")
+	for i, m := range req.Messages {
+		excerpt := []rune(oneLine.Replace(m.Content))
+		if len(excerpt) > 80 {
+			excerpt = append(excerpt[:80], '…')
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\n", i, m.Role, rep.MessageTokens[i], string(excerpt))
+	}
+	_ = tw.Flush()
+
+	if rep.ContextTokens > 0 {
+		fmt.Fprintf(w, "\nprompt: %d tokens of %d (%s)\n", rep.PromptTokens, rep.ContextTokens, ctxutil.PercentUsed(rep.PromptTokens, rep.ContextTokens))
+	} else {
+		fmt.Fprintf(w, "\nprompt: %d tokens (context window unknown)\n", rep.PromptTokens)
+	}
+	for _, n := range rep.Notes {
+		fmt.Fprintf(w, "note: %s\n", n)
+	}
+	if len(rep.Payload) > 0 {
+		fmt.Fprintln(w, "use /context --json for the request body the provider would receive")
+	}
+}
@@ -3,11 +3,13 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/yourname/clichat/internal/config"
-	"github.com/yourname/clichat/internal/provider/litellm"
+	"github.com/yourname/clichat/internal/provider"
 )
 
 func init() {
@@ -17,28 +19,42 @@ func init() {
 
 var modelsCmd = &cobra.Command{
 	Use:   "models",
-	Short: "List available models from LiteLLM",
+	Short: "List available models from the provider",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.Load()
 		if err != nil {
 			return err
 		}
-		client := litellm.NewClient(cfg.LiteLLMBaseURL, cfg.LiteLLMAPIKey)
-		mods, err := client.ListModels(context.Background())
+		prov, err := provider.New(cfg)
 		if err != nil {
 			return err
 		}
-		for _, m := range mods {
-			name := m.Name
-			if name == "" {
-				name = m.ID
-			}
-			fmt.Println(name)
+		mods, err := prov.ListModels(context.Background())
+		if err != nil {
+			return err
 		}
+		printModels(os.Stdout, mods)
 		return nil
 	},
 }
 
+// printModels writes one model per line, with size, family, parameters and quantization when
+// the provider reports them.
+func printModels(w io.Writer, mods []provider.Model) {
+	for _, m := range mods {
+		name := m.Name
+		if name == "" {
+			name = m.ID
+		}
+		if m.Size == 0 && m.Family == "" {
+			fmt.Fprintln(w, name)
+			continue
+		}
+		details := strings.Join(strings.Fields(strings.Join([]string{m.Family, m.ParameterSize, m.Quantization}, " ")), " ")
+		fmt.Fprintf(w, "%-32s %10s  %s\n", name, formatBytes(m.Size), details)
+	}
+}
+
 var modelCmd = &cobra.Command{
 	Use:   "model <name>",
 	Short: "Set default model",
@@ -64,8 +80,11 @@ var modelCmd = &cobra.Command{
 		if err != nil {
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		}
-		client := litellm.NewClient(cfg.LiteLLMBaseURL, cfg.LiteLLMAPIKey)
-		mods, err := client.ListModels(context.Background())
+		prov, err := provider.New(cfg)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		mods, err := prov.ListModels(context.Background())
 		if err != nil {
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		}
@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/yourname/clichat/internal/persona"
+)
+
+// printPersonas lists the available personas with their model and description.
+func printPersonas(w io.Writer, set persona.Set, dir string) {
+	if len(set) == 0 {
+		fmt.Fprintf(w, "no personas defined (add <name>.json files to %s)\n", dir)
+		return
+	}
+	for _, name := range set.Names() {
+		p := set[name]
+		model := p.Model
+		if model == "" {
+			model = "active model"
+		}
+		fmt.Fprintf(w, "  %-16s %-20s %s\n", name, model, p.Description)
+	}
+}
@@ -0,0 +1,8 @@
+package cli
+
+// Providers register themselves with the provider package; LLM_PROVIDER selects one.
+import (
+	_ "github.com/yourname/clichat/internal/provider/anthropic"
+	_ "github.com/yourname/clichat/internal/provider/litellm"
+	_ "github.com/yourname/clichat/internal/provider/ollama"
+)
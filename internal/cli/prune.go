@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yourname/clichat/internal/config"
+	"github.com/yourname/clichat/internal/memory/sqlite"
+)
+
+var (
+	pruneOlderThan    string
+	pruneConversation string
+	pruneDryRun       bool
+)
+
+func init() {
+	pruneCmd.Flags().StringVar(&pruneOlderThan, "older-than", "", "remove messages older than this age, e.g. 90d, 2w, 36h (default: RETENTION)")
+	pruneCmd.Flags().StringVarP(&pruneConversation, "conversation", "c", "", "only prune this conversation")
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "report what would be removed without deleting")
+	rootCmd.AddCommand(pruneCmd)
+}
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old history and reclaim disk space",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		age := cfg.Retention
+		if pruneOlderThan != "" {
+			if age, err = config.ParseAge(pruneOlderThan); err != nil {
+				return err
+			}
+		}
+		if age <= 0 {
+			return errors.New("--older-than is required when RETENTION is not set")
+		}
+		store, err := sqlite.Open(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		res, err := store.Prune(sqlite.PruneOptions{Before: time.Now().Add(-age), ConversationID: pruneConversation, DryRun: pruneDryRun})
+		if err != nil {
+			return err
+		}
+		verb := "removed"
+		if pruneDryRun {
+			verb = "would remove"
+		}
+		fmt.Printf("%s %d messages (%s of content) and %d empty conversations\n", verb, res.Messages, formatBytes(res.ContentBytes), res.Conversations)
+		if !pruneDryRun {
+			fmt.Printf("reclaimed %s on disk\n", formatBytes(res.ReclaimedBytes))
+		}
+		return nil
+	},
+}
+
+// applyRetention prunes history older than cfg.Retention; it is a no-op when retention is disabled.
+// It reports to stderr so it never mixes into a command's output, e.g. an export to stdout.
+func applyRetention(cfg *config.Config, store *sqlite.Store) error {
+	if cfg.Retention <= 0 {
+		return nil
+	}
+	res, err := store.Prune(sqlite.PruneOptions{Before: time.Now().Add(-cfg.Retention)})
+	if err != nil {
+		return fmt.Errorf("retention: %w", err)
+	}
+	if res.Messages > 0 || res.Conversations > 0 {
+		fmt.Fprintf(os.Stderr, "retention: removed %d messages and %d conversations older than %s (reclaimed %s)\n", res.Messages, res.Conversations, formatAge(cfg.Retention), formatBytes(res.ReclaimedBytes))
+	}
+	return nil
+}
+
+// formatAge renders whole days as "90d" and anything else as a time.Duration.
+func formatAge(d time.Duration) string {
+	if day := 24 * time.Hour; d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
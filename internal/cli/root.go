@@ -5,11 +5,29 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/yourname/clichat/internal/config"
+	"github.com/yourname/clichat/internal/memory/sqlite"
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "clichat",
 	Short: "CLI LLM chat bot",
+	// Every command that touches history enforces RETENTION first; prune applies its own age
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if cmd == pruneCmd {
+			return nil
+		}
+		cfg, err := config.Load()
+		if err != nil || cfg.Retention <= 0 {
+			return err
+		}
+		store, err := sqlite.Open(cfg.DBPath)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		return applyRetention(cfg, store)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		fmt.Println("clichat: use the chat command or run interactively")
 		return nil
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yourname/clichat/internal/memory/sqlite"
+)
+
+var (
+	searchConversation string
+	searchLimit        int
+)
+
+func init() {
+	searchCmd.Flags().StringVarP(&searchConversation, "conversation", "c", "", "only search this conversation")
+	searchCmd.Flags().IntVarP(&searchLimit, "limit", "n", 20, "maximum number of hits")
+	rootCmd.AddCommand(searchCmd)
+}
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Full-text search across conversation history",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withStore(func(store *sqlite.Store) error {
+			hits, err := store.Search(strings.Join(args, " "), searchConversation, searchLimit)
+			if err != nil {
+				return err
+			}
+			printSearchHits(os.Stdout, hits)
+			return nil
+		})
+	},
+}
+
+// printSearchHits writes ranked hits with the conversation and message ids and a highlighted snippet.
+func printSearchHits(w io.Writer, hits []sqlite.SearchHit) {
+	if len(hits) == 0 {
+		fmt.Fprintln(w, "no matches")
+		return
+	}
+	hl := strings.NewReplacer(sqlite.SnippetStart, "\x1b[1;33m", sqlite.SnippetEnd, "\x1b[0m", "\n", " ")
+	for _, h := range hits {
+		title := ""
+		if h.ConversationTitle != "" && h.ConversationTitle != h.ConversationID {
+			title = " " + h.ConversationTitle
+		}
+		fmt.Fprintf(w, "[%s] #%d %s %s%s\n", h.ConversationID, h.MessageID, h.Role, formatTime(h.CreatedAt), title)
+		fmt.Fprintf(w, "    %s\n", hl.Replace(h.Snippet))
+	}
+}
+
+// printMemoryHits writes recalled messages with their source, similarity and a one-line excerpt.
+func printMemoryHits(w io.Writer, hits []sqlite.MemoryHit) {
+	if len(hits) == 0 {
+		fmt.Fprintln(w, "nothing recalled")
+		return
+	}
+	oneLine := strings.NewReplacer("\n", " ", "\r", " ")
+	for _, h := range hits {
+		title := ""
+		if h.ConversationTitle != "" && h.ConversationTitle != h.ConversationID {
+			title = " " + h.ConversationTitle
+		}
+		excerpt := []rune(oneLine.Replace(h.Content))
+		if len(excerpt) > 200 {
+			excerpt = append(excerpt[:200], '…')
+		}
+		fmt.Fprintf(w, "[%s] #%d %s %s (score %.2f)%s\n", h.ConversationID, h.ID, h.Role, formatTime(h.CreatedAt), h.Score, title)
+		fmt.Fprintf(w, "    %s\n", string(excerpt))
+	}
+}
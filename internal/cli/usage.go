@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yourname/clichat/internal/config"
+	"github.com/yourname/clichat/internal/memory/sqlite"
+)
+
+var (
+	usageSince        string
+	usageBy           string
+	usageConversation string
+	usageCSV          bool
+)
+
+func init() {
+	usageCmd.Flags().StringVar(&usageSince, "since", "30d", "only count requests newer than this age, e.g. 7d, 2w, 36h; 0 for all time")
+	usageCmd.Flags().StringVar(&usageBy, "by", "day", "group by model, day, conversation or kind")
+	usageCmd.Flags().StringVarP(&usageConversation, "conversation", "c", "", "only count this conversation")
+	usageCmd.Flags().BoolVar(&usageCSV, "csv", false, "write CSV instead of a table")
+	rootCmd.AddCommand(usageCmd)
+}
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report token usage and cost of provider requests",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := sqlite.UsageOptions{By: usageBy, ConversationID: usageConversation}
+		if usageSince != "0" {
+			age, err := config.ParseAge(usageSince)
+			if err != nil {
+				return err
+			}
+			opts.Since = time.Now().Add(-age)
+		}
+		return withStore(func(store *sqlite.Store) error {
+			rows, err := store.Usage(opts)
+			if err != nil {
+				return err
+			}
+			if usageCSV {
+				return writeUsageCSV(os.Stdout, usageBy, rows)
+			}
+			printUsage(os.Stdout, usageBy, rows)
+			return nil
+		})
+	},
+}
+
+// printUsage writes usage rows as a table with a total line.
+func printUsage(w io.Writer, by string, rows []sqlite.UsageRow) {
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "no usage recorded")
+		return
+	}
+	var total sqlite.UsageRow
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tREQUESTS\tPROMPT\tCOMPLETION\tTOTAL\tCOST\n", usageHeader(by))
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\n", usageKey(r.Key), r.Requests, r.PromptTokens, r.CompletionTokens, r.TotalTokens, formatCost(r.Cost))
+		total.Requests += r.Requests
+		total.PromptTokens += r.PromptTokens
+		total.CompletionTokens += r.CompletionTokens
+		total.TotalTokens += r.TotalTokens
+		total.Cost += r.Cost
+	}
+	if len(rows) > 1 {
+		fmt.Fprintf(tw, "total\t%d\t%d\t%d\t%d\t%s\n", total.Requests, total.PromptTokens, total.CompletionTokens, total.TotalTokens, formatCost(total.Cost))
+	}
+	_ = tw.Flush()
+}
+
+// writeUsageCSV writes usage rows as CSV with the cost in USD.
+func writeUsageCSV(w io.Writer, by string, rows []sqlite.UsageRow) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{by, "requests", "prompt_tokens", "completion_tokens", "total_tokens", "cost_usd"})
+	for _, r := range rows {
+		_ = cw.Write([]string{r.Key, strconv.Itoa(r.Requests), strconv.Itoa(r.PromptTokens), strconv.Itoa(r.CompletionTokens),
+			strconv.Itoa(r.TotalTokens), strconv.FormatFloat(r.Cost, 'f', 6, 64)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func usageHeader(by string) string {
+	switch by {
+	case "model":
+		return "MODEL"
+	case "conversation":
+		return "CONVERSATION"
+	case "kind":
+		return "KIND"
+	}
+	return "DAY"
+}
+
+func usageKey(k string) string {
+	if k == "" {
+		return "(unknown)"
+	}
+	return k
+}
+
+// formatCost renders USD with enough precision for single cheap requests.
+func formatCost(usd float64) string {
+	if usd > 0 && usd < 0.01 {
+		return fmt.Sprintf("$%.4f", usd)
+	}
+	return fmt.Sprintf("$%.2f", usd)
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -22,8 +25,62 @@ type Config struct {
 	DropSamplingParams      bool
 	DebugPrompts            bool
 	AllowLocalShell         bool
+	AutoTitle               bool
+	TitleModel              string
+	// Retention is the age after which history is pruned at startup; zero disables it.
+	Retention time.Duration
+	// HistoryMaxTokens caps the history sent per request; zero derives it from ModelContextTokens.
+	HistoryMaxTokens int
+	// AnswerReserveTokens is kept free in the context window for the model's answer.
+	AnswerReserveTokens int
+	// TokenizerDir holds *.tiktoken vocabularies for encodings not compiled into the binary.
+	TokenizerDir string
+	// ContextOverflow is what happens to a request too large for the model's window: one of
+	// OverflowWarn, OverflowTrim or OverflowRefuse.
+	ContextOverflow string
+	// AutoSummarize condenses history that no longer fits into a running summary.
+	AutoSummarize bool
+	SummaryModel  string
+	// ModelInfo enables looking up context windows and supported parameters per model from
+	// LiteLLM's /model/info; results are cached in ModelInfoCache. MODEL_CONTEXT_TOKENS overrides them.
+	ModelInfo      bool
+	ModelInfoCache string
+	// EmbeddingModel enables semantic recall across conversations; empty disables it.
+	EmbeddingModel string
+	// RecallTopK and RecallMinScore bound how many recalled messages a request carries.
+	RecallTopK     int
+	RecallMinScore float64
+	// RecallWindow is how many of the most recent embedded messages recall searches; 0 means all.
+	RecallWindow int
+	// PricingFile holds local model prices (USD per million tokens) that override LiteLLM's.
+	PricingFile string
+	// PersonasDir holds one JSON file per persona (see package persona).
+	PersonasDir string
+	// Anthropic Messages API settings for LLM_PROVIDER=anthropic; AnthropicMaxTokens caps each answer.
+	AnthropicBaseURL   string
+	AnthropicAPIKey    string
+	AnthropicMaxTokens int
+	// Ollama settings for LLM_PROVIDER=ollama; OllamaNumCtx is the context window requested per model.
+	OllamaBaseURL string
+	OllamaNumCtx  int
+	// Provider request timeouts per phase (connect, until the first data, between chunks) and
+	// retries of requests failing before any data; zero disables a timeout.
+	ConnectTimeout    time.Duration
+	FirstTokenTimeout time.Duration
+	IdleTimeout       time.Duration
+	MaxRetries        int
 }
 
+// Context overflow policies.
+const (
+	// OverflowWarn sends oversized requests anyway after a warning.
+	OverflowWarn = "warn"
+	// OverflowTrim drops the oldest history turns until the request fits.
+	OverflowTrim = "trim"
+	// OverflowRefuse sends nothing and reports the overflow.
+	OverflowRefuse = "refuse"
+)
+
 // Load returns configuration with env values and sane defaults.
 func Load() (*Config, error) {
 	_ = godotenv.Load()
@@ -32,6 +89,9 @@ func Load() (*Config, error) {
 		Provider:                getenvDefault("LLM_PROVIDER", "litellm"),
 		LiteLLMBaseURL:          getenvDefault("LITELLM_BASE_URL", "http://localhost:4000"),
 		LiteLLMAPIKey:           os.Getenv("LITELLM_API_KEY"),
+		AnthropicBaseURL:        getenvDefault("ANTHROPIC_BASE_URL", "https://api.anthropic.com"),
+		AnthropicAPIKey:         os.Getenv("ANTHROPIC_API_KEY"),
+		OllamaBaseURL:           getenvDefault("OLLAMA_BASE_URL", "http://localhost:11434"),
 		Model:                   os.Getenv("LLM_MODEL"),
 		DBPath:                  getenvDefault("DB_PATH", "clichat.db"),
 		SystemPrompt:            getenvDefault("SYSTEM_PROMPT", "You are a concise, helpful CLI assistant."),
@@ -39,15 +99,71 @@ func Load() (*Config, error) {
 		DropSamplingParams:      getBool("DROP_SAMPLING_PARAMS", false),
 		DebugPrompts:            getBool("DEBUG_PROMPTS", false),
 		AllowLocalShell:         getBool("ALLOW_LOCAL_SHELL", false),
+		AutoTitle:               getBool("AUTO_TITLE", true),
+		TitleModel:              os.Getenv("TITLE_MODEL"),
+		AutoSummarize:           getBool("AUTO_SUMMARIZE", true),
+		SummaryModel:            os.Getenv("SUMMARY_MODEL"),
+		ModelInfo:               getBool("MODEL_INFO", true),
+		ModelInfoCache:          getenvDefault("MODEL_INFO_CACHE", "model_info.json"),
+		PricingFile:             os.Getenv("PRICING_FILE"),
+		TokenizerDir:            os.Getenv("TOKENIZER_DIR"),
+		PersonasDir:             getenvDefault("PERSONAS_DIR", "personas"),
+		EmbeddingModel:          os.Getenv("EMBEDDING_MODEL"),
 	}
 
 	cfg.Temperature = getFloat("TEMPERATURE", 0.2)
 	cfg.TopP = getFloat("TOP_P", 1.0)
 	cfg.ModelContextTokens = getInt("MODEL_CONTEXT_TOKENS", 0)
+	cfg.HistoryMaxTokens = getInt("HISTORY_MAX_TOKENS", 0)
+	cfg.AnswerReserveTokens = getInt("ANSWER_RESERVE_TOKENS", 1024)
+	cfg.AnthropicMaxTokens = getInt("ANTHROPIC_MAX_TOKENS", 8192)
+	cfg.OllamaNumCtx = getInt("OLLAMA_NUM_CTX", 8192)
+	cfg.ConnectTimeout = getDuration("LLM_CONNECT_TIMEOUT", 10*time.Second)
+	cfg.FirstTokenTimeout = getDuration("LLM_FIRST_TOKEN_TIMEOUT", 2*time.Minute)
+	cfg.IdleTimeout = getDuration("LLM_IDLE_TIMEOUT", time.Minute)
+	cfg.MaxRetries = getInt("LLM_MAX_RETRIES", 3)
+	cfg.RecallTopK = getInt("RECALL_TOP_K", 4)
+	cfg.RecallMinScore = getFloat("RECALL_MIN_SCORE", 0.35)
+	cfg.RecallWindow = getInt("RECALL_WINDOW", 5000)
+
+	cfg.ContextOverflow = strings.ToLower(getenvDefault("CONTEXT_OVERFLOW", OverflowTrim))
+	switch cfg.ContextOverflow {
+	case OverflowWarn, OverflowTrim, OverflowRefuse:
+	default:
+		return nil, fmt.Errorf("CONTEXT_OVERFLOW: want warn, trim or refuse, got %q", cfg.ContextOverflow)
+	}
+
+	if v := os.Getenv("RETENTION"); v != "" {
+		d, err := ParseAge(v)
+		if err != nil {
+			return nil, fmt.Errorf("RETENTION: %w", err)
+		}
+		cfg.Retention = d
+	}
 
 	return cfg, nil
 }
 
+// ParseAge parses an age such as "90d", "2w" or "36h". Besides the units accepted by
+// time.ParseDuration it understands d (days) and w (weeks).
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.ParseFloat(n, 64)
+			if err != nil || v < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(v * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 90d, 2w, 36h)", s)
+	}
+	return d, nil
+}
+
 func getenvDefault(key, def string) string {
 	v := os.Getenv(key)
 	if v == "" {
@@ -80,6 +196,18 @@ func getInt(key string, def int) int {
 	return i
 }
 
+func getDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return def
+	}
+	return d
+}
+
 func getBool(key string, def bool) bool {
 	v := os.Getenv(key)
 	if v == "" {
@@ -0,0 +1,218 @@
+package ctxutil
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// vocabFS holds the gzipped BPE rank files compiled into the binary (see vocab/README.md).
+//
+//go:generate sh -c "for e in cl100k_base o200k_base; do curl -fsSL https://openaipublic.blob.core.windows.net/encodings/$e.tiktoken | gzip -9n > vocab/$e.tiktoken.gz || exit 1; done"
+//go:embed vocab
+var vocabFS embed.FS
+
+// maxPieceBytes bounds the bytes merged at once. Merging is quadratic in the length of a
+// piece, so longer ones (a pasted base64 blob, say) are counted in chunks, which can
+// overcount them by a token per chunk.
+const maxPieceBytes = 256
+
+// Encoding is a byte-level BPE tokenizer compatible with OpenAI's tiktoken encodings.
+type Encoding struct {
+	name  string
+	ranks map[string]int
+	split func(r []rune, i int) int
+}
+
+// Name returns the encoding name, e.g. "cl100k_base".
+func (e *Encoding) Name() string { return e.name }
+
+// Count returns the number of tokens text encodes to.
+func (e *Encoding) Count(text string) int {
+	r := []rune(text)
+	total := 0
+	for i := 0; i < len(r); {
+		n := e.split(r, i)
+		total += e.countPiece([]byte(string(r[i : i+n])))
+		i += n
+	}
+	return total
+}
+
+// countPiece returns how many tokens one pre-tokenized piece encodes to.
+func (e *Encoding) countPiece(piece []byte) int {
+	if _, ok := e.ranks[string(piece)]; ok {
+		return 1
+	}
+	total := 0
+	for len(piece) > maxPieceBytes {
+		total += e.merge(piece[:maxPieceBytes])
+		piece = piece[maxPieceBytes:]
+	}
+	return total + e.merge(piece)
+}
+
+// merge merges the bytes of piece, always joining the adjacent pair with the lowest rank
+// first, and returns how many tokens remain.
+func (e *Encoding) merge(piece []byte) int {
+	if len(piece) < 2 {
+		return len(piece)
+	}
+	// bounds[k] is the start offset of the k-th part; the last entry is len(piece)
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	// pairs[k] is the rank of parts k and k+1 joined, math.MaxInt if that is no token
+	pairRank := func(k int) int {
+		if rank, ok := e.ranks[string(piece[bounds[k]:bounds[k+2]])]; ok {
+			return rank
+		}
+		return math.MaxInt
+	}
+	pairs := make([]int, len(bounds)-2)
+	for k := range pairs {
+		pairs[k] = pairRank(k)
+	}
+	for len(pairs) > 0 {
+		best, at := math.MaxInt, -1
+		for k, rank := range pairs {
+			if rank < best {
+				best, at = rank, k
+			}
+		}
+		if at < 0 {
+			break
+		}
+		bounds = append(bounds[:at+1], bounds[at+2:]...)
+		pairs = append(pairs[:at], pairs[at+1:]...)
+		// Only the pairs next to the joined part change
+		if at < len(pairs) {
+			pairs[at] = pairRank(at)
+		}
+		if at > 0 {
+			pairs[at-1] = pairRank(at - 1)
+		}
+	}
+	return len(bounds) - 1
+}
+
+// parseRanks reads the tiktoken rank format: one "<base64 token> <rank>" pair per line.
+func parseRanks(data []byte) (map[string]int, error) {
+	ranks := make(map[string]int, 200000)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; sc.Scan(); line++ {
+		tok, rank, ok := bytes.Cut(sc.Bytes(), []byte(" "))
+		if !ok {
+			if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: missing rank", line)
+		}
+		b, err := base64.StdEncoding.DecodeString(string(tok))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		n, err := strconv.Atoi(string(rank))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		ranks[string(b)] = n
+	}
+	return ranks, sc.Err()
+}
+
+var encodings = struct {
+	sync.Mutex
+	dir    string
+	loaded map[string]*Encoding
+	failed map[string]error
+}{loaded: map[string]*Encoding{}, failed: map[string]error{}}
+
+// SetTokenizerDir sets a directory whose rank files replace the embedded ones (TOKENIZER_DIR).
+// Encodings that failed to load are retried.
+func SetTokenizerDir(dir string) {
+	encodings.Lock()
+	defer encodings.Unlock()
+	encodings.dir = dir
+	clear(encodings.failed)
+}
+
+var splitters = map[string]func(r []rune, i int) int{
+	"cl100k_base": splitCL100K,
+	"o200k_base":  splitO200K,
+}
+
+// LoadEncoding returns the named BPE encoding. Rank files are read from the directory given to
+// SetTokenizerDir if it has one for the encoding, else from the embedded vocab directory.
+// Results, including failures, are cached.
+func LoadEncoding(name string) (*Encoding, error) {
+	encodings.Lock()
+	defer encodings.Unlock()
+	if e, ok := encodings.loaded[name]; ok {
+		return e, nil
+	}
+	if err, ok := encodings.failed[name]; ok {
+		return nil, err
+	}
+	e, err := loadEncoding(name, encodings.dir)
+	if err != nil {
+		encodings.failed[name] = err
+		return nil, err
+	}
+	encodings.loaded[name] = e
+	return e, nil
+}
+
+func loadEncoding(name, dir string) (*Encoding, error) {
+	split, ok := splitters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+	file := name + ".tiktoken"
+	var (
+		data []byte
+		err  error
+	)
+	if dir != "" {
+		data, err = os.ReadFile(filepath.Join(dir, file))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("encoding %s: %w", name, err)
+		}
+	}
+	if data == nil {
+		if data, err = readGzip(vocabFS, "vocab/"+file+".gz"); err != nil {
+			return nil, fmt.Errorf("encoding %s: vocabulary not embedded: %w", name, err)
+		}
+	}
+	ranks, err := parseRanks(data)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s: %w", name, err)
+	}
+	return &Encoding{name: name, ranks: ranks, split: split}, nil
+}
+
+// readGzip returns the decompressed contents of a gzipped file in fsys.
+func readGzip(fsys fs.FS, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,156 @@
+package ctxutil
+
+import "unicode"
+
+// The BPE encodings split text into pieces with a regular expression before merging bytes.
+// Go's regexp has no look-ahead, so the patterns are implemented by hand. Each split function
+// tries the pattern's alternatives in order at the current position, like the regex engine,
+// and returns the length in runes of the first one that matches.
+
+// splitCL100K mirrors the cl100k_base pattern:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func splitCL100K(r []rune, i int) int {
+	if n := contraction(r, i); n > 0 {
+		return n
+	}
+	if n := optionalPrefix(r, i, func(j int) int { return run(r, j, unicode.IsLetter) }); n > 0 {
+		return n
+	}
+	if n := numbers(r, i); n > 0 {
+		return n
+	}
+	if n := punctuation(r, i, isNewline); n > 0 {
+		return n
+	}
+	return whitespace(r, i)
+}
+
+// splitO200K mirrors the o200k_base pattern:
+//
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?
+//	|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?
+//	|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func splitO200K(r []rune, i int) int {
+	lowerWord := func(j int) int {
+		// [upper]*[lower]+ with backtracking, since Lm, Lo and M belong to both classes
+		for p := j + run(r, j, isUpperish); p >= j; p-- {
+			if n := run(r, p, isLowerish); n > 0 {
+				end := p + n
+				return end - j + contraction(r, end)
+			}
+		}
+		return 0
+	}
+	if n := optionalPrefix(r, i, lowerWord); n > 0 {
+		return n
+	}
+	upperWord := func(j int) int {
+		u := run(r, j, isUpperish)
+		if u == 0 {
+			return 0
+		}
+		end := j + u + run(r, j+u, isLowerish)
+		return end - j + contraction(r, end)
+	}
+	if n := optionalPrefix(r, i, upperWord); n > 0 {
+		return n
+	}
+	if n := numbers(r, i); n > 0 {
+		return n
+	}
+	if n := punctuation(r, i, func(c rune) bool { return isNewline(c) || c == '/' }); n > 0 {
+		return n
+	}
+	return whitespace(r, i)
+}
+
+// optionalPrefix matches [^\r\n\p{L}\p{N}]? followed by body, preferring to take the prefix.
+func optionalPrefix(r []rune, i int, body func(j int) int) int {
+	if i < len(r) && !isNewline(r[i]) && !unicode.IsLetter(r[i]) && !unicode.IsNumber(r[i]) {
+		if n := body(i + 1); n > 0 {
+			return n + 1
+		}
+	}
+	return body(i)
+}
+
+// contraction matches (?i:'s|'t|'re|'ve|'m|'ll|'d).
+func contraction(r []rune, i int) int {
+	if i+1 >= len(r) || r[i] != '\'' {
+		return 0
+	}
+	a := unicode.ToLower(r[i+1])
+	switch a {
+	case 's', 't', 'm', 'd':
+		return 2
+	}
+	if i+2 < len(r) {
+		b := unicode.ToLower(r[i+2])
+		switch {
+		case a == 'r' && b == 'e', a == 'v' && b == 'e', a == 'l' && b == 'l':
+			return 3
+		}
+	}
+	return 0
+}
+
+// numbers matches \p{N}{1,3}.
+func numbers(r []rune, i int) int {
+	n := run(r, i, unicode.IsNumber)
+	if n > 3 {
+		return 3
+	}
+	return n
+}
+
+// punctuation matches " ?[^\s\p{L}\p{N}]+" followed by any run of trailing characters.
+func punctuation(r []rune, i int, trailing func(rune) bool) int {
+	j := i
+	if j < len(r) && r[j] == ' ' {
+		j++
+	}
+	n := run(r, j, func(c rune) bool { return !unicode.IsSpace(c) && !unicode.IsLetter(c) && !unicode.IsNumber(c) })
+	if n == 0 {
+		return 0
+	}
+	j += n
+	return j + run(r, j, trailing) - i
+}
+
+// whitespace matches \s*[\r\n]+, then \s+(?!\S), then \s+.
+func whitespace(r []rune, i int) int {
+	n := run(r, i, unicode.IsSpace)
+	if n == 0 {
+		// Not reachable for valid text: every rune is a letter, number, space or other.
+		return 1
+	}
+	for k := i + n - 1; k >= i; k-- {
+		if isNewline(r[k]) {
+			return k + 1 - i
+		}
+	}
+	if i+n == len(r) || n == 1 {
+		return n
+	}
+	// Leave the last space to be merged with the following word
+	return n - 1
+}
+
+func run(r []rune, i int, in func(rune) bool) int {
+	j := i
+	for j < len(r) && in(r[j]) {
+		j++
+	}
+	return j - i
+}
+
+func isNewline(c rune) bool { return c == '\r' || c == '\n' }
+
+func isUpperish(c rune) bool {
+	return unicode.In(c, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+func isLowerish(c rune) bool {
+	return unicode.In(c, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
@@ -0,0 +1,76 @@
+package ctxutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tokenizer counts the tokens a model sees for a piece of text.
+type Tokenizer interface {
+	Count(text string) int
+	Name() string
+}
+
+// Per-message overhead of the chat format: every message is wrapped in <|start|>, the role
+// (one token for system, user and assistant) and <|message|>...<|end|>, and every reply is
+// primed with <|start|>assistant<|message|>.
+const (
+	TokensPerMessage = 4
+	TokensPerReply   = 3
+)
+
+type heuristic struct{}
+
+func (heuristic) Count(text string) int { return EstimateTokens(text) }
+func (heuristic) Name() string          { return "heuristic" }
+
+// Heuristic is the ~4 characters per token estimate used for models without a known encoding.
+var Heuristic Tokenizer = heuristic{}
+
+// EncodingForModel returns the name of the BPE encoding a model uses, or "" if unknown.
+// Provider prefixes such as "openai/" or "azure/" are ignored.
+func EncodingForModel(model string) string {
+	m := strings.ToLower(model)
+	if i := strings.LastIndex(m, "/"); i >= 0 {
+		m = m[i+1:]
+	}
+	for _, p := range []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4", "chatgpt-4o"} {
+		if strings.HasPrefix(m, p) {
+			return "o200k_base"
+		}
+	}
+	for _, p := range []string{"gpt-4", "gpt-3.5", "gpt-35", "text-embedding-3", "text-embedding-ada-002"} {
+		if strings.HasPrefix(m, p) {
+			return "cl100k_base"
+		}
+	}
+	return ""
+}
+
+// ForModel returns the tokenizer for model, or Heuristic when the model's encoding is unknown.
+// It panics if the vocabulary of a known encoding cannot be loaded: they are embedded, so that
+// is a broken build or a broken TOKENIZER_DIR file, not a reason to estimate.
+func ForModel(model string) Tokenizer {
+	name := EncodingForModel(model)
+	if name == "" {
+		return Heuristic
+	}
+	enc, err := LoadEncoding(name)
+	if err != nil {
+		panic(fmt.Sprintf("ctxutil: %v", err))
+	}
+	return enc
+}
+
+// CountMessages returns the prompt tokens of a chat request with the given message contents,
+// including the per-message and reply overhead.
+func CountMessages(tok Tokenizer, contents []string) int {
+	if len(contents) == 0 {
+		return 0
+	}
+	total := TokensPerReply
+	for _, c := range contents {
+		total += TokensPerMessage + tok.Count(c)
+	}
+	return total
+}
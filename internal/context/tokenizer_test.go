@@ -0,0 +1,136 @@
+package ctxutil
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func pieces(split func([]rune, int) int, text string) []string {
+	r := []rune(text)
+	var out []string
+	for i := 0; i < len(r); {
+		n := split(r, i)
+		out = append(out, string(r[i:i+n]))
+		i += n
+	}
+	return out
+}
+
+func TestPretokenize(t *testing.T) {
+	cases := []struct {
+		split func([]rune, int) int
+		text  string
+		want  []string
+	}{
+		{splitCL100K, "Hello world", []string{"Hello", " world"}},
+		{splitCL100K, "I'm here, aren't you?", []string{"I", "'m", " here", ",", " aren", "'t", " you", "?"}},
+		{splitCL100K, "12345 apples", []string{"123", "45", " apples"}},
+		{splitCL100K, "a  b\n\nc  ", []string{"a", " ", " b", "\n\n", "c", "  "}},
+		{splitCL100K, "x = (y+1);\n", []string{"x", " =", " (", "y", "+", "1", ");\n"}},
+		{splitO200K, "HelloWorld don't", []string{"Hello", "World", " don't"}},
+		{splitO200K, "path/to/file", []string{"path", "/to", "/file"}},
+		{splitO200K, "ABCdef", []string{"ABCdef"}},
+	}
+	for _, c := range cases {
+		if got := pieces(c.split, c.text); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("split %q = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestBPEMerge(t *testing.T) {
+	// All single bytes, then merges in rank order. "cd" outranks "bc", so "abcde" becomes
+	// a b cd e -> ab cd e -> abcd e; merging left to right would get stuck at a bc d e.
+	var sb strings.Builder
+	for b := 0; b < 256; b++ {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b)
+	}
+	for i, tok := range []string{"cd", "ab", "bc", "abcd", " x"} {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(tok)), 256+i)
+	}
+	ranks, err := parseRanks([]byte(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := &Encoding{name: "test", ranks: ranks, split: splitCL100K}
+	cases := map[string]int{
+		"":       0,
+		"ab":     1,
+		"abc":    2, // ab c
+		"abcd":   1, // whole piece is a token
+		"abcde":  2,
+		"bcb":    2, // bc b
+		"ab x":   2,
+		"ab  x":  3, // "ab", " ", " x"
+		"héllo":  6, // multi-byte runes are split into bytes
+		"\n\n\n": 3,
+	}
+	for text, want := range cases {
+		if got := enc.Count(text); got != want {
+			t.Errorf("Count(%q) = %d, want %d", text, got, want)
+		}
+	}
+	// One 200k-byte piece is merged in chunks instead of all at once
+	if got := enc.Count(strings.Repeat("ab", 100000)); got != 100000 {
+		t.Errorf("long piece = %d tokens, want 100000", got)
+	}
+}
+
+func TestEncodingForModel(t *testing.T) {
+	cases := map[string]string{
+		"gpt-4o-mini":            "o200k_base",
+		"openai/gpt-5":           "o200k_base",
+		"o3-mini":                "o200k_base",
+		"gpt-4.1":                "o200k_base",
+		"gpt-4-turbo":            "cl100k_base",
+		"azure/gpt-35-turbo":     "cl100k_base",
+		"text-embedding-3-small": "cl100k_base",
+		"claude-3-5-sonnet":      "",
+		"llama3":                 "",
+	}
+	for model, want := range cases {
+		if got := EncodingForModel(model); got != want {
+			t.Errorf("EncodingForModel(%q) = %q, want %q", model, got, want)
+		}
+	}
+	if ForModel("llama3") != Heuristic {
+		t.Error("unknown model should use the heuristic")
+	}
+}
+
+func TestKnownTokenCounts(t *testing.T) {
+	cases := []struct {
+		encoding string
+		text     string
+		want     int
+	}{
+		{"cl100k_base", "hello world", 2},
+		{"cl100k_base", "tiktoken is great!", 6},
+		{"cl100k_base", "antidisestablishmentarianism", 6},
+		{"cl100k_base", "2 + 2 = 4", 7},
+		{"cl100k_base", "お誕生日おめでとう", 9},
+		{"o200k_base", "hello world", 2},
+	}
+	for _, c := range cases {
+		enc, err := LoadEncoding(c.encoding)
+		if err != nil {
+			t.Fatalf("vocabulary not available: %v", err)
+		}
+		if got := enc.Count(c.text); got != c.want {
+			t.Errorf("%s: Count(%q) = %d, want %d", c.encoding, c.text, got, c.want)
+		}
+	}
+}
+
+func TestCountMessages(t *testing.T) {
+	if got := CountMessages(Heuristic, nil); got != 0 {
+		t.Errorf("empty = %d", got)
+	}
+	// 2 messages of 1 and 2 heuristic tokens, 4 overhead each, 3 for the reply
+	if got := CountMessages(Heuristic, []string{"hi", "hello"}); got != 1+2+2*TokensPerMessage+TokensPerReply {
+		t.Errorf("CountMessages = %d", got)
+	}
+}
@@ -0,0 +1,155 @@
+// Package export renders stored conversations as Markdown, JSON, JSONL or standalone HTML.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yourname/clichat/internal/memory/sqlite"
+)
+
+// Supported export formats.
+const (
+	FormatMarkdown = "md"
+	FormatJSON     = "json"
+	FormatJSONL    = "jsonl"
+	FormatHTML     = "html"
+)
+
+// Formats lists the supported export formats.
+var Formats = []string{FormatMarkdown, FormatJSON, FormatJSONL, FormatHTML}
+
+// Conversation is the exported form of a conversation and its messages.
+type Conversation struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+	Messages  []Message `json:"messages"`
+}
+
+// Message is the exported form of a single message.
+type Message struct {
+	ID        int64     `json:"id"`
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Model     string    `json:"model,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Document is the top-level JSON export.
+type Document struct {
+	Version       int            `json:"version"`
+	ExportedAt    time.Time      `json:"exported_at"`
+	Conversations []Conversation `json:"conversations"`
+}
+
+// FromStore converts a stored conversation and its messages to the export form.
+func FromStore(c *sqlite.Conversation, msgs []sqlite.Message) Conversation {
+	out := Conversation{ID: c.ID, Title: c.Title, CreatedAt: c.CreatedAt, Messages: make([]Message, 0, len(msgs))}
+	for _, m := range msgs {
+		out.Messages = append(out.Messages, Message{ID: m.ID, Role: m.Role, Content: m.Content, Model: m.Model, CreatedAt: m.CreatedAt})
+	}
+	return out
+}
+
+// FormatFromPath infers the export format from a file extension; it returns "" when unknown.
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return FormatMarkdown
+	case ".json":
+		return FormatJSON
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	case ".html", ".htm":
+		return FormatHTML
+	}
+	return ""
+}
+
+// Write renders convs to w in the given format.
+func Write(w io.Writer, format string, convs []Conversation) error {
+	switch format {
+	case FormatMarkdown:
+		return WriteMarkdown(w, convs)
+	case FormatJSON:
+		return WriteJSON(w, convs)
+	case FormatJSONL:
+		return WriteJSONL(w, convs)
+	case FormatHTML:
+		return WriteHTML(w, convs)
+	}
+	return fmt.Errorf("unknown export format %q (want one of %s)", format, strings.Join(Formats, ", "))
+}
+
+// WriteJSON writes a single JSON document holding all conversations.
+func WriteJSON(w io.Writer, convs []Conversation) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(Document{Version: 1, ExportedAt: time.Now().UTC(), Conversations: convs})
+}
+
+// WriteJSONL writes one conversation per line, each with an OpenAI-style messages array.
+func WriteJSONL(w io.Writer, convs []Conversation) error {
+	enc := json.NewEncoder(w)
+	for _, c := range convs {
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMarkdown writes each conversation as a heading followed by its messages.
+func WriteMarkdown(w io.Writer, convs []Conversation) error {
+	for i, c := range convs {
+		if i > 0 {
+			if _, err := fmt.Fprint(w, "\n---\n\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "# %s\n\n- Conversation: `%s`\n- Created: %s\n\n", displayTitle(c), c.ID, formatTime(c.CreatedAt)); err != nil {
+			return err
+		}
+		for _, m := range c.Messages {
+			heading := roleLabel(m.Role)
+			if m.Model != "" {
+				heading += " (" + m.Model + ")"
+			}
+			if _, err := fmt.Fprintf(w, "## %s\n\n_%s_\n\n%s\n\n", heading, formatTime(m.CreatedAt), strings.TrimSpace(m.Content)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func displayTitle(c Conversation) string {
+	if c.Title != "" {
+		return c.Title
+	}
+	return c.ID
+}
+
+func roleLabel(role string) string {
+	switch role {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	case "system":
+		return "System"
+	}
+	return role
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.UTC().Format("2006-01-02 15:04:05 UTC")
+}
@@ -0,0 +1,81 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleConversations() []Conversation {
+	ts := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []Conversation{{
+		ID:        "c1",
+		Title:     "Nginx reload",
+		CreatedAt: ts,
+		Messages: []Message{
+			{ID: 1, Role: "user", Content: "how do I reload <nginx>?", CreatedAt: ts},
+			{ID: 2, Role: "assistant", Content: "nginx -s reload", Model: "gpt-4o", CreatedAt: ts.Add(time.Second)},
+		},
+	}}
+}
+
+func TestWriteFormats(t *testing.T) {
+	convs := sampleConversations()
+	for _, format := range Formats {
+		var buf bytes.Buffer
+		if err := Write(&buf, format, convs); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		out := buf.String()
+		if !strings.Contains(out, "gpt-4o") {
+			t.Fatalf("%s: model missing from output:\n%s", format, out)
+		}
+		switch format {
+		case FormatHTML:
+			if strings.Contains(out, "<nginx>") || !strings.Contains(out, "&lt;nginx&gt;") {
+				t.Fatalf("html: content not escaped:\n%s", out)
+			}
+		case FormatMarkdown:
+			if !strings.Contains(out, "# Nginx reload") || !strings.Contains(out, "2025-01-02 03:04:05 UTC") {
+				t.Fatalf("md: unexpected output:\n%s", out)
+			}
+		}
+	}
+	if err := Write(&bytes.Buffer{}, "pdf", convs); err == nil {
+		t.Fatalf("want error for unknown format")
+	}
+}
+
+func TestWriteJSONLRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSONL(&buf, sampleConversations()); err != nil {
+		t.Fatalf("WriteJSONL: %v", err)
+	}
+	sc := bufio.NewScanner(&buf)
+	lines := 0
+	for sc.Scan() {
+		lines++
+		var c Conversation
+		if err := json.Unmarshal(sc.Bytes(), &c); err != nil {
+			t.Fatalf("line %d: %v", lines, err)
+		}
+		if len(c.Messages) != 2 || c.Messages[1].Role != "assistant" || !c.Messages[1].CreatedAt.Equal(time.Date(2025, 1, 2, 3, 4, 6, 0, time.UTC)) {
+			t.Fatalf("unexpected conversation: %+v", c)
+		}
+	}
+	if lines != 1 {
+		t.Fatalf("want 1 line, got %d", lines)
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	cases := map[string]string{"a.md": FormatMarkdown, "a.JSON": FormatJSON, "a.jsonl": FormatJSONL, "a.html": FormatHTML, "a.txt": "", "-": ""}
+	for in, want := range cases {
+		if got := FormatFromPath(in); got != want {
+			t.Errorf("FormatFromPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+// htmlPage is a self-contained page: inline CSS, no scripts, no external assets.
+var htmlPage = template.Must(template.New("page").Funcs(template.FuncMap{
+	"title": displayTitle,
+	"role":  roleLabel,
+	"time":  formatTime,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{if eq (len .Conversations) 1}}{{title (index .Conversations 0)}}{{else}}clichat export{{end}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 52rem; margin: 2rem auto; padding: 0 1rem; color: #1f2328; background: #fff; }
+header.conv { border-bottom: 1px solid #d0d7de; margin-top: 3rem; }
+header.conv h1 { margin-bottom: .25rem; }
+.meta { color: #656d76; font-size: .85rem; }
+.msg { border: 1px solid #d0d7de; border-radius: 6px; margin: 1rem 0; padding: .75rem 1rem; }
+.msg.user { background: #f6f8fa; }
+.msg.system { background: #fff8c5; }
+.msg .who { font-weight: 600; margin-bottom: .5rem; }
+.msg .body { white-space: pre-wrap; word-wrap: break-word; font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: .9rem; }
+footer { color: #656d76; font-size: .8rem; margin: 3rem 0 1rem; }
+</style>
+</head>
+<body>
+{{range .Conversations}}
+<section>
+<header class="conv">
+<h1>{{title .}}</h1>
+<p class="meta">Conversation <code>{{.ID}}</code> &middot; created {{time .CreatedAt}} &middot; {{len .Messages}} messages</p>
+</header>
+{{range .Messages}}
+<article class="msg {{.Role}}" id="m{{.ID}}">
+<div class="who">{{role .Role}}{{if .Model}} <span class="meta">{{.Model}}</span>{{end}} <span class="meta">&middot; {{time .CreatedAt}}</span></div>
+<div class="body">{{.Content}}</div>
+</article>
+{{end}}
+</section>
+{{end}}
+<footer>Exported from clichat on {{time .ExportedAt}}</footer>
+</body>
+</html>
+`))
+
+// WriteHTML writes a standalone, read-only HTML page with all conversations.
+func WriteHTML(w io.Writer, convs []Conversation) error {
+	return htmlPage.Execute(w, Document{Version: 1, ExportedAt: time.Now().UTC(), Conversations: convs})
+}
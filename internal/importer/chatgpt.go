@@ -0,0 +1,152 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// chatGPTConversation mirrors an entry of conversations.json in a ChatGPT data export.
+type chatGPTConversation struct {
+	ID             string                 `json:"id"`
+	ConversationID string                 `json:"conversation_id"`
+	Title          string                 `json:"title"`
+	CreateTime     float64                `json:"create_time"`
+	CurrentNode    string                 `json:"current_node"`
+	Mapping        map[string]chatGPTNode `json:"mapping"`
+}
+
+type chatGPTNode struct {
+	ID       string          `json:"id"`
+	Parent   string          `json:"parent"`
+	Children []string        `json:"children"`
+	Message  *chatGPTMessage `json:"message"`
+}
+
+type chatGPTMessage struct {
+	ID     string `json:"id"`
+	Author struct {
+		Role string `json:"role"`
+	} `json:"author"`
+	CreateTime float64 `json:"create_time"`
+	Content    struct {
+		ContentType string            `json:"content_type"`
+		Parts       []json.RawMessage `json:"parts"`
+	} `json:"content"`
+	Metadata struct {
+		ModelSlug string `json:"model_slug"`
+	} `json:"metadata"`
+}
+
+// ParseChatGPT reads conversations.json from a ChatGPT data export. Only the branch
+// ending at each conversation's current node is imported, and only text parts are kept.
+func ParseChatGPT(r io.Reader) ([]Conversation, error) {
+	var raw []chatGPTConversation
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+	out := make([]Conversation, 0, len(raw))
+	for _, rc := range raw {
+		extID := rc.ConversationID
+		if extID == "" {
+			extID = rc.ID
+		}
+		if extID == "" {
+			return nil, errors.New("chatgpt export: conversation without id")
+		}
+		c := Conversation{ID: chatGPTConversationID(extID), Title: rc.Title, CreatedAt: unixTime(rc.CreateTime)}
+		for _, node := range rc.branch() {
+			m := node.Message
+			role := m.Author.Role
+			if role != "user" && role != "assistant" && role != "system" {
+				continue
+			}
+			text := strings.TrimSpace(m.text())
+			if text == "" {
+				continue
+			}
+			msgID := m.ID
+			if msgID == "" {
+				msgID = node.ID
+			}
+			c.Messages = append(c.Messages, Message{
+				ExternalID: "chatgpt:" + extID + ":" + msgID,
+				Role:       role,
+				Content:    text,
+				Model:      m.Metadata.ModelSlug,
+				CreatedAt:  unixTime(m.CreateTime),
+			})
+		}
+		if c.CreatedAt.IsZero() && len(c.Messages) > 0 {
+			c.CreatedAt = c.Messages[0].CreatedAt
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// chatGPTConversationID keeps imported ids short enough to type while staying stable.
+func chatGPTConversationID(extID string) string {
+	short := strings.ReplaceAll(extID, "-", "")
+	if len(short) > 12 {
+		short = short[:12]
+	}
+	return "chatgpt-" + short
+}
+
+// branch walks from the current node up to the root and returns the nodes with messages, oldest first.
+func (c chatGPTConversation) branch() []chatGPTNode {
+	cur := c.CurrentNode
+	if cur == "" {
+		cur = c.lastLeaf()
+	}
+	var path []chatGPTNode
+	seen := map[string]bool{}
+	for cur != "" && !seen[cur] {
+		seen[cur] = true
+		node, ok := c.Mapping[cur]
+		if !ok {
+			break
+		}
+		if node.Message != nil {
+			path = append(path, node)
+		}
+		cur = node.Parent
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// lastLeaf follows the last child from the root for exports without current_node.
+func (c chatGPTConversation) lastLeaf() string {
+	var cur string
+	for id, n := range c.Mapping {
+		if n.Parent == "" {
+			cur = id
+			break
+		}
+	}
+	for i := 0; cur != "" && i < len(c.Mapping); i++ {
+		n := c.Mapping[cur]
+		if len(n.Children) == 0 {
+			break
+		}
+		cur = n.Children[len(n.Children)-1]
+	}
+	return cur
+}
+
+// text joins the string parts of a message; non-text parts such as images are skipped.
+func (m *chatGPTMessage) text() string {
+	var parts []string
+	for _, p := range m.Content.Parts {
+		var s string
+		if err := json.Unmarshal(p, &s); err == nil && s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,145 @@
+// Package importer reads conversations from ChatGPT data exports and OpenAI-style JSONL
+// and loads them into the sqlite store without duplicating previously imported messages.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/yourname/clichat/internal/memory/sqlite"
+)
+
+// Supported import formats.
+const (
+	FormatChatGPT = "chatgpt"
+	FormatJSONL   = "jsonl"
+)
+
+// Formats lists the supported import formats.
+var Formats = []string{FormatChatGPT, FormatJSONL}
+
+// Conversation is a parsed conversation ready to be stored.
+type Conversation struct {
+	ID        string
+	Title     string
+	CreatedAt time.Time
+	Messages  []Message
+}
+
+// Message is a parsed message. ExternalID must be stable across re-imports of the same file.
+type Message struct {
+	ExternalID string
+	Role       string
+	Content    string
+	Model      string
+	CreatedAt  time.Time
+}
+
+// Result summarises what Load stored.
+type Result struct {
+	Conversations     int
+	Messages          int
+	SkippedDuplicates int
+	// Renamed maps the ids of conversations stored under another id, because theirs was taken
+	// by an unrelated conversation, to the id used.
+	Renamed map[string]string
+}
+
+// Parse reads conversations in the given format.
+func Parse(r io.Reader, format string) ([]Conversation, error) {
+	switch format {
+	case FormatChatGPT:
+		return ParseChatGPT(r)
+	case FormatJSONL:
+		return ParseJSONL(r)
+	}
+	return nil, fmt.Errorf("unknown import format %q (want one of %s)", format, strings.Join(Formats, ", "))
+}
+
+// Load stores convs, creating conversations that don't exist yet and skipping messages
+// whose external id was already imported. A conversation is never appended to an existing
+// one it was not imported into before; it gets a fresh id instead.
+func Load(store *sqlite.Store, convs []Conversation) (Result, error) {
+	var res Result
+	for _, c := range convs {
+		id, err := targetConversation(store, c)
+		if err != nil {
+			return res, fmt.Errorf("%s: %w", c.ID, err)
+		}
+		if id != c.ID {
+			if res.Renamed == nil {
+				res.Renamed = map[string]string{}
+			}
+			res.Renamed[c.ID] = id
+		}
+		created, err := store.CreateConversation(&sqlite.Conversation{ID: id, Title: c.Title, CreatedAt: c.CreatedAt})
+		if err != nil {
+			return res, fmt.Errorf("%s: %w", c.ID, err)
+		}
+		if created {
+			res.Conversations++
+		}
+		for _, m := range c.Messages {
+			_, err := store.InsertMessage(&sqlite.Message{
+				ConversationID: id,
+				Role:           m.Role,
+				Content:        m.Content,
+				Model:          m.Model,
+				CreatedAt:      m.CreatedAt,
+				ExternalID:     m.ExternalID,
+			})
+			switch {
+			case errors.Is(err, sqlite.ErrDuplicateMessage):
+				res.SkippedDuplicates++
+			case err != nil:
+				return res, fmt.Errorf("%s: %w", c.ID, err)
+			default:
+				res.Messages++
+			}
+		}
+	}
+	return res, nil
+}
+
+// targetConversation returns the id to load c into: the conversation holding an earlier import
+// of it, else c.ID, or c.ID with an "-import" suffix when another conversation with messages
+// has that id.
+func targetConversation(store *sqlite.Store, c Conversation) (string, error) {
+	for _, m := range c.Messages {
+		id, err := store.ConversationByExternalID(m.ExternalID)
+		if !errors.Is(err, sqlite.ErrConversationNotFound) {
+			return id, err
+		}
+	}
+	for i := 1; ; i++ {
+		id := c.ID
+		switch {
+		case i == 2:
+			id += "-import"
+		case i > 2:
+			id += fmt.Sprintf("-import-%d", i-1)
+		}
+		conv, err := store.GetConversation(id)
+		if errors.Is(err, sqlite.ErrConversationNotFound) {
+			return id, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if conv.MessageCount == 0 {
+			return id, nil
+		}
+	}
+}
+
+// unixTime converts fractional epoch seconds, treating 0 as unknown.
+func unixTime(sec float64) time.Time {
+	if sec <= 0 {
+		return time.Time{}
+	}
+	whole := int64(sec)
+	return time.Unix(whole, int64((sec-float64(whole))*1e9)).UTC()
+}
@@ -0,0 +1,123 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourname/clichat/internal/memory/sqlite"
+)
+
+func TestParseChatGPT(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "chatgpt.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	convs, err := ParseChatGPT(f)
+	if err != nil {
+		t.Fatalf("ParseChatGPT: %v", err)
+	}
+	if len(convs) != 1 {
+		t.Fatalf("want 1 conversation, got %d", len(convs))
+	}
+	c := convs[0]
+	if c.ID != "chatgpt-6a1b2c3d0000" || c.Title != "Nginx reload" {
+		t.Fatalf("unexpected conversation: %+v", c)
+	}
+	if len(c.Messages) != 2 {
+		t.Fatalf("want user+assistant on the current branch, got %+v", c.Messages)
+	}
+	if m := c.Messages[1]; m.Content != "nginx -s reload" || m.Model != "gpt-4o" || !m.CreatedAt.Equal(time.Unix(1700000004, 0)) {
+		t.Fatalf("unexpected assistant message: %+v", m)
+	}
+}
+
+func TestLoadSkipsDuplicates(t *testing.T) {
+	st, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+
+	const data = `{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":[{"type":"text","text":"hello"}]}]}` + "\n" +
+		`{"id":"c2","title":"Second","created_at":"2024-05-01T10:00:00Z","messages":[{"id":7,"role":"user","content":"ping","created_at":"2024-05-01T10:00:00Z"}]}` + "\n"
+	for i := 0; i < 2; i++ {
+		convs, err := ParseJSONL(strings.NewReader(data))
+		if err != nil {
+			t.Fatalf("ParseJSONL: %v", err)
+		}
+		res, err := Load(st, convs)
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		want := Result{Conversations: 2, Messages: 3}
+		if i == 1 {
+			want = Result{SkippedDuplicates: 3}
+		}
+		if !reflect.DeepEqual(res, want) {
+			t.Fatalf("import %d: got %+v, want %+v", i+1, res, want)
+		}
+	}
+
+	conv, err := st.GetConversation("c2")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if conv.Title != "Second" || !conv.TitleLocked || !conv.CreatedAt.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected conversation: %+v", conv)
+	}
+	msgs, err := st.AllMessages("c2")
+	if err != nil || len(msgs) != 1 || !msgs[0].CreatedAt.Equal(conv.CreatedAt) {
+		t.Fatalf("unexpected messages: %+v (%v)", msgs, err)
+	}
+}
+
+func TestLoadIntoTakenID(t *testing.T) {
+	st, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+	if _, err := st.CreateOrGetConversation("default", "default"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := st.AppendMessage("default", "user", "native"); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	// An export of "default" with a tool call and an empty assistant message
+	const data = `{"id":"default","messages":[{"id":1,"role":"user","content":"native"},` +
+		`{"id":2,"role":"assistant","content":null,"tool_calls":[{"id":"t"}]},{"id":3,"role":"tool","content":"42"},` +
+		`{"id":4,"role":"assistant","content":"  "},{"id":5,"role":"assistant","content":"it is 42"}]}` + "\n"
+	for i := 0; i < 2; i++ {
+		convs, err := ParseJSONL(strings.NewReader(data))
+		if err != nil {
+			t.Fatalf("ParseJSONL: %v", err)
+		}
+		if n := len(convs[0].Messages); n != 2 {
+			t.Fatalf("want the user and the answer message only, got %+v", convs[0].Messages)
+		}
+		res, err := Load(st, convs)
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		// The re-import finds the conversation of the first one
+		want := Result{Conversations: 1, Messages: 2, Renamed: map[string]string{"default": "default-import"}}
+		if i == 1 {
+			want = Result{SkippedDuplicates: 2, Renamed: map[string]string{"default": "default-import"}}
+		}
+		if !reflect.DeepEqual(res, want) {
+			t.Fatalf("import %d: got %+v, want %+v", i+1, res, want)
+		}
+	}
+	if msgs, _ := st.AllMessages("default"); len(msgs) != 1 {
+		t.Fatalf("existing conversation changed: %+v", msgs)
+	}
+	if msgs, _ := st.AllMessages("default-import"); len(msgs) != 2 || msgs[1].Content != "it is 42" {
+		t.Fatalf("unexpected imported messages: %+v", msgs)
+	}
+}
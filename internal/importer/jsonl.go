@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// jsonlConversation is one line of an OpenAI-style JSONL file. Only messages is required;
+// the other fields match what `clichat export --format jsonl` writes.
+type jsonlConversation struct {
+	ID        string         `json:"id"`
+	Title     string         `json:"title"`
+	CreatedAt time.Time      `json:"created_at"`
+	Messages  []jsonlMessage `json:"messages"`
+}
+
+type jsonlMessage struct {
+	ID        json.RawMessage `json:"id"`
+	Role      string          `json:"role"`
+	Content   json.RawMessage `json:"content"`
+	Model     string          `json:"model"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ParseJSONL reads one conversation per line, each with a messages array.
+// Lines without an id get one derived from their content so re-imports stay stable.
+// Like ParseChatGPT it keeps user, assistant and system messages with text only.
+func ParseJSONL(r io.Reader) ([]Conversation, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	var out []Conversation
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var rc jsonlConversation
+		if err := json.Unmarshal([]byte(line), &rc); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if len(rc.Messages) == 0 {
+			return nil, fmt.Errorf("line %d: no messages", lineNo)
+		}
+		id := rc.ID
+		if id == "" {
+			sum := sha256.Sum256([]byte(line))
+			id = "import-" + hex.EncodeToString(sum[:6])
+		}
+		c := Conversation{ID: id, Title: rc.Title, CreatedAt: rc.CreatedAt}
+		for i, m := range rc.Messages {
+			if m.Role != "user" && m.Role != "assistant" && m.Role != "system" {
+				continue
+			}
+			text := jsonlContent(m.Content)
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			msgID := strings.Trim(string(m.ID), `"`)
+			if msgID == "" || msgID == "null" {
+				msgID = fmt.Sprint(i)
+			}
+			c.Messages = append(c.Messages, Message{
+				ExternalID: "jsonl:" + id + ":" + msgID,
+				Role:       m.Role,
+				Content:    text,
+				Model:      m.Model,
+				CreatedAt:  m.CreatedAt,
+			})
+		}
+		out = append(out, c)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// jsonlContent accepts either a string or an array of content parts, keeping text parts only.
+func jsonlContent(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return ""
+	}
+	var texts []string
+	for _, p := range parts {
+		if p.Type == "text" && p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
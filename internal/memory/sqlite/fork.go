@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+)
+
+// initForks adds message parent links and fork provenance, backfilling parents on existing databases.
+func (s *Store) initForks() error {
+	hadParent, err := s.hasColumn("messages", "parent_id")
+	if err != nil {
+		return err
+	}
+	if err := s.ensureColumn("messages", "parent_id", "INTEGER", "NULL"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("conversations", "forked_from", "TEXT", "NULL"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("conversations", "forked_from_message", "INTEGER", "NULL"); err != nil {
+		return err
+	}
+	if hadParent {
+		return nil
+	}
+	// Existing conversations are linear: each message follows the previous one.
+	_, err = s.db.Exec(`UPDATE messages SET parent_id = (
+		SELECT MAX(p.id) FROM messages p WHERE p.conversation_id = messages.conversation_id AND p.id < messages.id)`)
+	return err
+}
+
+// ForkConversation creates conversation newID whose history is a copy of the source
+// conversation up to and including messageID, and records where it was forked from.
+func (s *Store) ForkConversation(newID string, messageID int64) (*Conversation, error) {
+	if newID == "" {
+		return nil, errors.New("conversation id required")
+	}
+	at, err := s.GetMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+	src, err := s.GetConversation(at.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.GetConversation(newID); err == nil {
+		return nil, fmt.Errorf("conversation %q already exists", newID)
+	} else if !errors.Is(err, ErrConversationNotFound) {
+		return nil, err
+	}
+	path, err := s.messagePath(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	title := src.Title
+	if title == "" {
+		title = src.ID
+	}
+	// The fork keeps the persona so it continues in the same voice
+	if _, err := tx.Exec(`INSERT INTO conversations(id, title, forked_from, forked_from_message, persona, last_active_at) VALUES(?, ?, ?, ?, ?, `+nowExpr+`)`,
+		newID, title+" (fork)", src.ID, messageID, src.Persona); err != nil {
+		return nil, err
+	}
+	var parent any
+	for _, m := range path {
+		res, err := tx.Exec(`INSERT INTO messages(conversation_id, role, content, model, created_at, parent_id, pinned) VALUES(?, ?, ?, NULLIF(?, ''), ?, ?, ?)`,
+			newID, m.Role, m.Content, m.Model, m.CreatedAt.UTC().Format(timeLayout), parent, m.Pinned)
+		if err != nil {
+			return nil, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		parent = id
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetConversation(newID)
+}
+
+// messagePath returns the chain of parents ending at messageID, oldest first.
+func (s *Store) messagePath(messageID int64) ([]Message, error) {
+	rows, err := s.db.Query(`WITH RECURSIVE path(id, depth) AS (
+			SELECT id, 0 FROM messages WHERE id = ?
+			UNION ALL
+			SELECT m.parent_id, p.depth + 1 FROM messages m JOIN path p ON m.id = p.id WHERE m.parent_id IS NOT NULL
+		)
+		SELECT m.id, m.conversation_id, m.role, m.content, m.model, m.created_at, m.parent_id, m.superseded, m.pinned, m.prompt_tokens, m.completion_tokens, m.total_tokens, m.cost
+		FROM path JOIN messages m ON m.id = path.id ORDER BY path.depth DESC`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
@@ -0,0 +1,165 @@
+package sqlite
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// MemoryHit is a message from another conversation that is semantically close to a query.
+type MemoryHit struct {
+	Message
+	ConversationTitle string
+	// Score is the cosine similarity between the message and the query, in [-1, 1].
+	Score float64
+}
+
+// initMemory creates the table holding one embedding vector per message and model, and the
+// trigger that drops vectors with their message.
+func (s *Store) initMemory() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS embeddings (
+		message_id INTEGER NOT NULL,
+		model TEXT NOT NULL,
+		vector BLOB NOT NULL,
+		PRIMARY KEY (message_id, model)
+	);
+	CREATE INDEX IF NOT EXISTS idx_embeddings_model ON embeddings(model, message_id);
+	CREATE TRIGGER IF NOT EXISTS embeddings_ad AFTER DELETE ON messages BEGIN
+		DELETE FROM embeddings WHERE message_id = old.id;
+	END;
+	CREATE TRIGGER IF NOT EXISTS embeddings_au AFTER UPDATE OF content ON messages BEGIN
+		DELETE FROM embeddings WHERE message_id = old.id;
+	END;`)
+	return err
+}
+
+// SetRecall turns recall on or off for a conversation.
+func (s *Store) SetRecall(conversationID string, on bool) error {
+	res, err := s.db.Exec(`UPDATE conversations SET recall = ? WHERE id = ?`, on, conversationID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// UnembeddedMessages returns up to limit current user and assistant messages, newest first,
+// that have no embedding for model yet.
+func (s *Store) UnembeddedMessages(model string, limit int) ([]Message, error) {
+	rows, err := s.db.Query(messageSelect+` WHERE role IN ('user', 'assistant') AND superseded = 0 AND content != ''
+		AND NOT EXISTS (SELECT 1 FROM embeddings e WHERE e.message_id = messages.id AND e.model = ?)
+		ORDER BY id DESC LIMIT ?`, model, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
+
+// SaveEmbedding stores the embedding of a message. Vectors are normalized so that similarity
+// is a dot product.
+func (s *Store) SaveEmbedding(messageID int64, model string, vector []float32) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO embeddings(message_id, model, vector) VALUES(?, ?, ?)`,
+		messageID, model, encodeVector(normalize(vector)))
+	return err
+}
+
+// SimilarOptions selects the messages SimilarMessages compares with a query.
+type SimilarOptions struct {
+	// Model is the embedding model whose vectors are compared.
+	Model string
+	// K is the number of hits returned; hits scoring below MinScore are left out.
+	K        int
+	MinScore float64
+	// ExcludeConversation leaves out the messages of one conversation, usually the current one.
+	ExcludeConversation string
+	// Window only scans the vectors of this many most recent messages; zero scans all.
+	Window int
+}
+
+// SimilarMessages returns the current messages with embeddings closest to query, best first.
+func (s *Store) SimilarMessages(query []float32, opts SimilarOptions) ([]MemoryHit, error) {
+	q := normalize(query)
+	limit := opts.Window
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.db.Query(`SELECT e.message_id, e.vector FROM embeddings e JOIN messages m ON m.id = e.message_id
+		WHERE e.model = ? AND m.superseded = 0 AND m.conversation_id != ?
+		ORDER BY e.message_id DESC LIMIT ?`, opts.Model, opts.ExcludeConversation, limit)
+	if err != nil {
+		return nil, err
+	}
+	type scored struct {
+		id    int64
+		score float64
+	}
+	var best []scored
+	for rows.Next() {
+		var (
+			id   int64
+			blob []byte
+		)
+		if err := rows.Scan(&id, &blob); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		// Stored vectors are normalized, so the dot product is the cosine similarity
+		if len(blob) != 4*len(q) {
+			continue
+		}
+		if dot := dotVector(blob, q); dot >= opts.MinScore {
+			best = append(best, scored{id, dot})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(best, func(i, j int) bool { return best[i].score > best[j].score })
+	if len(best) > opts.K {
+		best = best[:opts.K]
+	}
+	hits := make([]MemoryHit, 0, len(best))
+	for _, b := range best {
+		m, err := scanMessage(s.db.QueryRow(messageSelect+` WHERE id = ?`, b.id))
+		if err != nil {
+			return nil, err
+		}
+		h := MemoryHit{Message: *m, Score: b.score}
+		_ = s.db.QueryRow(`SELECT COALESCE(title, '') FROM conversations WHERE id = ?`, m.ConversationID).Scan(&h.ConversationTitle)
+		hits = append(hits, h)
+	}
+	return hits, nil
+}
+
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	n := float32(math.Sqrt(sum))
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = x / n
+	}
+	return out
+}
+
+func encodeVector(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(x))
+	}
+	return b
+}
+
+// dotVector returns the dot product of an encoded vector and v without decoding it.
+func dotVector(b []byte, v []float32) float64 {
+	var dot float64
+	for i, x := range v {
+		dot += float64(math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))) * float64(x)
+	}
+	return dot
+}
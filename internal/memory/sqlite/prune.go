@@ -0,0 +1,99 @@
+package sqlite
+
+import "time"
+
+// PruneOptions selects the messages removed by Prune.
+type PruneOptions struct {
+	// Before removes messages created before this time.
+	Before time.Time
+	// ConversationID limits pruning to one conversation; empty means all.
+	ConversationID string
+	// DryRun reports what would be removed without deleting anything.
+	DryRun bool
+}
+
+// PruneResult reports what Prune removed (or would remove, for a dry run).
+type PruneResult struct {
+	Messages      int
+	Conversations int
+	// ContentBytes is the total size of the removed message contents.
+	ContentBytes int64
+	// ReclaimedBytes is how much the database shrank after VACUUM; zero for dry runs.
+	ReclaimedBytes int64
+}
+
+// Prune deletes messages older than opts.Before, removes conversations left empty that have
+// had no activity since then, and vacuums the database to return the space to the filesystem.
+// Running summaries covering a deleted message are dropped too, since they retell its content;
+// the chat rebuilds them from the remaining history.
+func (s *Store) Prune(opts PruneOptions) (PruneResult, error) {
+	var res PruneResult
+	cutoff := opts.Before.UTC().Format(timeLayout)
+	scope := ` AND (? = '' OR conversation_id = ?)`
+	err := s.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(LENGTH(CAST(content AS BLOB))), 0) FROM messages WHERE created_at < ?`+scope,
+		cutoff, opts.ConversationID, opts.ConversationID).Scan(&res.Messages, &res.ContentBytes)
+	if err != nil {
+		return res, err
+	}
+	// Conversations whose every message is pruned and that were last active before the cutoff
+	convScope := ` AND (? = '' OR c.id = ?)`
+	emptyAfter := `FROM conversations c WHERE COALESCE(c.last_active_at, c.created_at) < ?` + convScope + `
+		AND NOT EXISTS (SELECT 1 FROM messages m WHERE m.conversation_id = c.id AND m.created_at >= ?)`
+	if err := s.db.QueryRow(`SELECT COUNT(*) `+emptyAfter, cutoff, opts.ConversationID, opts.ConversationID, cutoff).Scan(&res.Conversations); err != nil {
+		return res, err
+	}
+	if opts.DryRun || (res.Messages == 0 && res.Conversations == 0) {
+		return res, nil
+	}
+
+	before, err := s.sizeBytes()
+	if err != nil {
+		return res, err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM conversations WHERE id IN (SELECT c.id `+emptyAfter+`)`, cutoff, opts.ConversationID, opts.ConversationID, cutoff); err != nil {
+		return res, err
+	}
+	if _, err := tx.Exec(`UPDATE conversations SET summary = NULL, summary_through = 0
+		WHERE summary_through > 0 AND (? = '' OR id = ?) AND EXISTS (SELECT 1 FROM messages m
+			WHERE m.conversation_id = conversations.id AND m.created_at < ? AND m.id <= conversations.summary_through)`,
+		opts.ConversationID, opts.ConversationID, cutoff); err != nil {
+		return res, err
+	}
+	if _, err := tx.Exec(`DELETE FROM messages WHERE created_at < ?`+scope, cutoff, opts.ConversationID, opts.ConversationID); err != nil {
+		return res, err
+	}
+	if err := tx.Commit(); err != nil {
+		return res, err
+	}
+	if _, err := s.db.Exec(`INSERT INTO messages_fts(messages_fts) VALUES ('optimize')`); err != nil {
+		return res, err
+	}
+	if _, err := s.db.Exec(`VACUUM`); err != nil {
+		return res, err
+	}
+	after, err := s.sizeBytes()
+	if err != nil {
+		return res, err
+	}
+	if before > after {
+		res.ReclaimedBytes = before - after
+	}
+	return res, nil
+}
+
+// sizeBytes returns the size of the main database file.
+func (s *Store) sizeBytes() (int64, error) {
+	var pages, pageSize int64
+	if err := s.db.QueryRow(`PRAGMA page_count`).Scan(&pages); err != nil {
+		return 0, err
+	}
+	if err := s.db.QueryRow(`PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return 0, err
+	}
+	return pages * pageSize, nil
+}
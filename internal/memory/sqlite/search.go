@@ -0,0 +1,108 @@
+package sqlite
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Markers wrapped around matched terms in SearchHit.Snippet.
+const (
+	SnippetStart = "\x02"
+	SnippetEnd   = "\x03"
+)
+
+// SearchHit is a message matching a full-text query.
+type SearchHit struct {
+	MessageID         int64
+	ConversationID    string
+	ConversationTitle string
+	Role              string
+	Snippet           string
+	CreatedAt         time.Time
+	Rank              float64
+}
+
+// initSearch creates the FTS5 index over messages.content, the triggers that keep it
+// in sync, and backfills it when the index is created on an existing database.
+func (s *Store) initSearch() error {
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'messages_fts'`).Scan(&n); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(content, content='messages', content_rowid='id');
+	CREATE TRIGGER IF NOT EXISTS messages_fts_ai AFTER INSERT ON messages BEGIN
+		INSERT INTO messages_fts(rowid, content) VALUES (new.id, new.content);
+	END;
+	CREATE TRIGGER IF NOT EXISTS messages_fts_ad AFTER DELETE ON messages BEGIN
+		INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+	END;
+	CREATE TRIGGER IF NOT EXISTS messages_fts_au AFTER UPDATE OF content ON messages BEGIN
+		INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+		INSERT INTO messages_fts(rowid, content) VALUES (new.id, new.content);
+	END;`)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		_, err = s.db.Exec(`INSERT INTO messages_fts(messages_fts) VALUES ('rebuild')`)
+	}
+	return err
+}
+
+// Search runs a full-text query over message contents, best matches first.
+// Each whitespace-separated term must match; a trailing '*' makes a term a prefix match.
+// An empty conversationID searches all conversations.
+func (s *Store) Search(query, conversationID string, limit int) ([]SearchHit, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+	rows, err := s.db.Query(`SELECT m.id, m.conversation_id, c.title, m.role,
+		snippet(messages_fts, 0, ?, ?, '…', 12), m.created_at, bm25(messages_fts)
+		FROM messages_fts
+		JOIN messages m ON m.id = messages_fts.rowid
+		LEFT JOIN conversations c ON c.id = m.conversation_id
+		WHERE messages_fts MATCH ? AND (? = '' OR m.conversation_id = ?)
+		ORDER BY bm25(messages_fts) LIMIT ?`, SnippetStart, SnippetEnd, match, conversationID, conversationID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []SearchHit
+	for rows.Next() {
+		var (
+			h       SearchHit
+			title   sql.NullString
+			created sql.NullTime
+		)
+		if err := rows.Scan(&h.MessageID, &h.ConversationID, &title, &h.Role, &h.Snippet, &created, &h.Rank); err != nil {
+			return nil, err
+		}
+		h.ConversationTitle = title.String
+		h.CreatedAt = created.Time
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// ftsQuery quotes each term so user input such as "nginx.conf" is not parsed as FTS syntax.
+func ftsQuery(q string) string {
+	var terms []string
+	for _, f := range strings.Fields(q) {
+		prefix := strings.HasSuffix(f, "*")
+		f = strings.TrimRight(f, "*")
+		if f == "" {
+			continue
+		}
+		t := `"` + strings.ReplaceAll(f, `"`, `""`) + `"`
+		if prefix {
+			t += "*"
+		}
+		terms = append(terms, t)
+	}
+	return strings.Join(terms, " ")
+}
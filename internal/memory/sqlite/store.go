@@ -3,10 +3,21 @@ package sqlite
 import (
 	"database/sql"
 	"errors"
+	"strings"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
 
+// ErrConversationNotFound is returned when a conversation id does not exist.
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// ErrMessageNotFound is returned when a message id does not exist.
+var ErrMessageNotFound = errors.New("message not found")
+
+// ErrDuplicateMessage is returned by InsertMessage when a message with the same ExternalID exists.
+var ErrDuplicateMessage = errors.New("duplicate message")
+
 type Store struct {
 	db *sql.DB
 }
@@ -16,6 +27,24 @@ type Message struct {
 	ConversationID string
 	Role           string
 	Content        string
+	// Model is the model that produced an assistant message; empty for other roles.
+	Model     string
+	CreatedAt time.Time
+	// ExternalID identifies imported messages so re-imports are skipped.
+	ExternalID string
+	// ParentID is the message this one follows; zero for the first message of a conversation.
+	ParentID int64
+	// Superseded marks an earlier variant replaced by /retry or /edit; it is kept but left out of history.
+	Superseded bool
+	// Pinned messages are always sent to the model regardless of history windowing.
+	Pinned bool
+	// Token usage of the request that produced an assistant message, as reported by the
+	// provider or estimated locally when it reported none; zero for other roles.
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	// Cost is the price of that request in USD; zero when the model has no known pricing.
+	Cost float64
 }
 
 type Conversation struct {
@@ -25,10 +54,31 @@ type Conversation struct {
 	ContextAnswerTokens int
 	PromptMessageCount  int
 	AnswerMessageCount  int
+	MessageCount        int
+	CreatedAt           time.Time
+	LastActiveAt        time.Time
+	// TitleLocked is set once a user picks a title; generated titles never overwrite it.
+	TitleLocked bool
+	// ForkedFrom and ForkedFromMessage record the conversation and message a fork was created from.
+	ForkedFrom        string
+	ForkedFromMessage int64
+	// Summary condenses the history up to and including message SummaryThrough.
+	Summary        string
+	SummaryThrough int64
+	// Recall injects similar messages from other conversations into requests (see Recall).
+	Recall bool
+	// Persona names the persona applied to requests; empty uses the global system prompt.
+	Persona string
 }
 
 func Open(path string) (*Store, error) {
-	db, err := sql.Open("sqlite", path)
+	// Writers wait for each other, e.g. the chat and a background title update, instead of
+	// failing with SQLITE_BUSY
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open("sqlite", path+sep+"_pragma=busy_timeout(5000)")
 	if err != nil {
 		return nil, err
 	}
@@ -70,7 +120,64 @@ func (s *Store) init() error {
 	if err := s.ensureColumn("conversations", "answer_message_count", "INTEGER", "0"); err != nil {
 		return err
 	}
-	return nil
+	if err := s.ensureColumn("conversations", "last_active_at", "TIMESTAMP", "NULL"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("conversations", "title_locked", "INTEGER", "0"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("messages", "model", "TEXT", "NULL"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("messages", "external_id", "TEXT", "NULL"); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_external_id ON messages(external_id) WHERE external_id IS NOT NULL`); err != nil {
+		return err
+	}
+	if err := s.initForks(); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("messages", "superseded", "INTEGER", "0"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("messages", "pinned", "INTEGER", "0"); err != nil {
+		return err
+	}
+	for _, col := range []string{"prompt_tokens", "completion_tokens", "total_tokens"} {
+		if err := s.ensureColumn("messages", col, "INTEGER", "0"); err != nil {
+			return err
+		}
+	}
+	if err := s.ensureColumn("messages", "cost", "REAL", "0"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("conversations", "summary", "TEXT", "NULL"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("conversations", "summary_through", "INTEGER", "0"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("conversations", "recall", "INTEGER", "1"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("conversations", "persona", "TEXT", "''"); err != nil {
+		return err
+	}
+	// Backfill activity for conversations created before the column existed
+	_, err = s.db.Exec(`UPDATE conversations SET last_active_at = COALESCE(
+		(SELECT MAX(m.created_at) FROM messages m WHERE m.conversation_id = conversations.id), created_at)
+		WHERE last_active_at IS NULL`)
+	if err != nil {
+		return err
+	}
+	if err := s.initSearch(); err != nil {
+		return err
+	}
+	if err := s.initUsage(); err != nil {
+		return err
+	}
+	return s.initMemory()
 }
 
 func (s *Store) ensureColumn(table, column, colType, defaultVal string) error {
@@ -114,18 +221,188 @@ func (s *Store) CreateOrGetConversation(id string, title string) (*Conversation,
 	if id == "" {
 		return nil, errors.New("conversation id required")
 	}
-	_, err := s.db.Exec(`INSERT OR IGNORE INTO conversations(id, title) VALUES(?, ?)`, id, title)
+	_, err := s.db.Exec(`INSERT OR IGNORE INTO conversations(id, title, last_active_at) VALUES(?, ?, `+nowExpr+`)`, id, title)
 	if err != nil {
 		return nil, err
 	}
-	row := s.db.QueryRow(`SELECT id, title, context_prompt_tokens, context_answer_tokens, prompt_message_count, answer_message_count FROM conversations WHERE id = ?`, id)
-	var c Conversation
-	if err := row.Scan(&c.ID, &c.Title, &c.ContextPromptTokens, &c.ContextAnswerTokens, &c.PromptMessageCount, &c.AnswerMessageCount); err != nil {
+	return s.GetConversation(id)
+}
+
+// GetConversation returns the conversation with the given id or ErrConversationNotFound.
+func (s *Store) GetConversation(id string) (*Conversation, error) {
+	row := s.db.QueryRow(conversationSelect+` WHERE c.id = ?`, id)
+	c, err := scanConversation(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrConversationNotFound
+	}
+	return c, err
+}
+
+// ListConversations returns all conversations, oldest first, with their message counts.
+func (s *Store) ListConversations() ([]Conversation, error) {
+	rows, err := s.db.Query(conversationSelect + ` ORDER BY c.created_at ASC, c.id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Conversation
+	for rows.Next() {
+		c, err := scanConversation(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *c)
+	}
+	return out, rows.Err()
+}
+
+// MostRecentConversation returns the conversation with the latest activity or ErrConversationNotFound.
+func (s *Store) MostRecentConversation() (*Conversation, error) {
+	row := s.db.QueryRow(conversationSelect + ` ORDER BY c.last_active_at DESC, c.rowid DESC LIMIT 1`)
+	c, err := scanConversation(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrConversationNotFound
+	}
+	return c, err
+}
+
+// nowExpr is a millisecond-precision timestamp so activity ordering survives fast successive writes.
+const nowExpr = `strftime('%Y-%m-%d %H:%M:%f', 'now')`
+
+const conversationSelect = `SELECT c.id, c.title, c.title_locked, c.forked_from, c.forked_from_message, c.summary, c.summary_through, c.recall, c.persona, c.created_at, c.last_active_at, c.context_prompt_tokens, c.context_answer_tokens, c.prompt_message_count, c.answer_message_count,
+		(SELECT COUNT(*) FROM messages m WHERE m.conversation_id = c.id AND m.superseded = 0)
+		FROM conversations c`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanConversation(r rowScanner) (*Conversation, error) {
+	var (
+		c       Conversation
+		title   sql.NullString
+		created sql.NullTime
+		active  sql.NullTime
+		forkC   sql.NullString
+		forkM   sql.NullInt64
+		summary sql.NullString
+	)
+	if err := r.Scan(&c.ID, &title, &c.TitleLocked, &forkC, &forkM, &summary, &c.SummaryThrough, &c.Recall, &c.Persona, &created, &active, &c.ContextPromptTokens, &c.ContextAnswerTokens, &c.PromptMessageCount, &c.AnswerMessageCount, &c.MessageCount); err != nil {
 		return nil, err
 	}
+	c.Title = title.String
+	c.CreatedAt = created.Time
+	c.LastActiveAt = active.Time
+	c.ForkedFrom = forkC.String
+	c.ForkedFromMessage = forkM.Int64
+	c.Summary = summary.String
 	return &c, nil
 }
 
+// ConversationByExternalID returns the id of the conversation holding the message imported with
+// externalID, or ErrConversationNotFound if no such message is stored.
+func (s *Store) ConversationByExternalID(externalID string) (string, error) {
+	var id string
+	err := s.db.QueryRow(`SELECT conversation_id FROM messages WHERE external_id = ?`, externalID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrConversationNotFound
+	}
+	return id, err
+}
+
+// CreateConversation inserts c with its title and creation time, reporting whether it was created.
+// An existing conversation with the same id is left unchanged. A non-empty title is locked.
+func (s *Store) CreateConversation(c *Conversation) (bool, error) {
+	if c.ID == "" {
+		return false, errors.New("conversation id required")
+	}
+	created := time.Now().UTC()
+	if !c.CreatedAt.IsZero() {
+		created = c.CreatedAt.UTC()
+	}
+	title := c.Title
+	locked := title != ""
+	if title == "" {
+		title = c.ID
+	}
+	ts := created.Format(timeLayout)
+	res, err := s.db.Exec(`INSERT OR IGNORE INTO conversations(id, title, title_locked, created_at, last_active_at) VALUES(?, ?, ?, ?, ?)`, c.ID, title, locked, ts, ts)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RenameConversation sets the display title of a conversation and locks it against generated titles.
+func (s *Store) RenameConversation(id, title string) error {
+	res, err := s.db.Exec(`UPDATE conversations SET title = ?, title_locked = 1 WHERE id = ?`, title, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// SetGeneratedTitle stores a model-generated title unless the user has set one.
+// It reports whether the title was written.
+func (s *Store) SetGeneratedTitle(id, title string) (bool, error) {
+	res, err := s.db.Exec(`UPDATE conversations SET title = ? WHERE id = ? AND title_locked = 0`, title, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteConversation removes a conversation and all of its messages.
+func (s *Store) DeleteConversation(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM messages WHERE conversation_id = ?`, id); err != nil {
+		return err
+	}
+	res, err := tx.Exec(`DELETE FROM conversations WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrConversationNotFound
+	}
+	return nil
+}
+
+// SetSummary stores the running summary of a conversation covering messages up to through.
+func (s *Store) SetSummary(conversationID, summary string, through int64) error {
+	res, err := s.db.Exec(`UPDATE conversations SET summary = NULLIF(?, ''), summary_through = ? WHERE id = ?`, summary, through, conversationID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// SetPersona attaches the named persona to a conversation; an empty name detaches it.
+func (s *Store) SetPersona(conversationID, persona string) error {
+	res, err := s.db.Exec(`UPDATE conversations SET persona = ? WHERE id = ?`, persona, conversationID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
 func (s *Store) UpdateContextUsage(conversationID string, promptTokens, answerTokens int) error {
 	_, err := s.db.Exec(`UPDATE conversations SET context_prompt_tokens = ?, context_answer_tokens = ? WHERE id = ?`, promptTokens, answerTokens, conversationID)
 	return err
@@ -137,38 +414,149 @@ func (s *Store) UpdateContextStats(conversationID string, promptTokens, answerTo
 }
 
 func (s *Store) AppendMessage(conversationID, role, content string) (int64, error) {
-	res, err := s.db.Exec(`INSERT INTO messages(conversation_id, role, content) VALUES(?, ?, ?)`, conversationID, role, content)
+	return s.InsertMessage(&Message{ConversationID: conversationID, Role: role, Content: content})
+}
+
+// InsertMessage stores m and returns its id. A zero CreatedAt means now.
+// Messages whose ExternalID is already stored are skipped with ErrDuplicateMessage.
+// The usage of an answer is also recorded in the usage table.
+func (s *Store) InsertMessage(m *Message) (int64, error) {
+	var created any
+	if !m.CreatedAt.IsZero() {
+		created = m.CreatedAt.UTC().Format(timeLayout)
+	}
+	res, err := s.db.Exec(`INSERT OR IGNORE INTO messages(conversation_id, role, content, model, created_at, external_id, parent_id, prompt_tokens, completion_tokens, total_tokens, cost)
+		VALUES(?, ?, ?, NULLIF(?, ''), COALESCE(?, CURRENT_TIMESTAMP), NULLIF(?, ''), COALESCE(NULLIF(?, 0), (SELECT MAX(id) FROM messages WHERE conversation_id = ? AND superseded = 0)), ?, ?, ?, ?)`,
+		m.ConversationID, m.Role, m.Content, m.Model, created, m.ExternalID, m.ParentID, m.ConversationID, m.PromptTokens, m.CompletionTokens, m.TotalTokens, m.Cost)
 	if err != nil {
 		return 0, err
 	}
-	return res.LastInsertId()
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, ErrDuplicateMessage
+	}
+	if created == nil {
+		err = s.touchConversation(m.ConversationID)
+	} else {
+		// Backdated messages only move activity forward, so imports don't look recent
+		_, err = s.db.Exec(`UPDATE conversations SET last_active_at = MAX(COALESCE(last_active_at, ''), ?) WHERE id = ?`, created, m.ConversationID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if m.Role == "assistant" && m.TotalTokens > 0 {
+		err = s.RecordUsage(&UsageRecord{
+			ConversationID: m.ConversationID, MessageID: id, Kind: UsageAnswer, Model: m.Model,
+			PromptTokens: m.PromptTokens, CompletionTokens: m.CompletionTokens, TotalTokens: m.TotalTokens,
+			Cost: m.Cost, CreatedAt: m.CreatedAt,
+		})
+	}
+	return id, err
+}
+
+// timeLayout matches the format sqlite uses for CURRENT_TIMESTAMP.
+const timeLayout = "2006-01-02 15:04:05"
+
+// touchConversation records activity on a conversation.
+func (s *Store) touchConversation(id string) error {
+	_, err := s.db.Exec(`UPDATE conversations SET last_active_at = `+nowExpr+` WHERE id = ?`, id)
+	return err
 }
 
+// ListMessages returns the most recent limit current messages of a conversation, oldest first.
 func (s *Store) ListMessages(conversationID string, limit int) ([]Message, error) {
 	if limit <= 0 {
 		limit = 100
 	}
-	rows, err := s.db.Query(`SELECT id, conversation_id, role, content FROM messages WHERE conversation_id = ? ORDER BY id ASC LIMIT ?`, conversationID, limit)
+	rows, err := s.db.Query(`SELECT * FROM (`+messageSelect+` WHERE conversation_id = ? AND superseded = 0 ORDER BY id DESC LIMIT ?) ORDER BY id ASC`, conversationID, limit)
 	if err != nil {
 		return nil, err
 	}
+	return scanMessages(rows)
+}
+
+// AllMessages returns every current message of a conversation, oldest first.
+func (s *Store) AllMessages(conversationID string) ([]Message, error) {
+	rows, err := s.db.Query(messageSelect+` WHERE conversation_id = ? AND superseded = 0 ORDER BY id ASC`, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
+
+const messageSelect = `SELECT id, conversation_id, role, content, model, created_at, parent_id, superseded, pinned, prompt_tokens, completion_tokens, total_tokens, cost FROM messages`
+
+func scanMessages(rows *sql.Rows) ([]Message, error) {
 	defer rows.Close()
 	var out []Message
 	for rows.Next() {
-		var m Message
-		if err := rows.Scan(&m.ID, &m.ConversationID, &m.Role, &m.Content); err != nil {
+		m, err := scanMessage(rows)
+		if err != nil {
 			return nil, err
 		}
-		out = append(out, m)
+		out = append(out, *m)
 	}
 	return out, rows.Err()
 }
 
+func scanMessage(r rowScanner) (*Message, error) {
+	var (
+		m       Message
+		model   sql.NullString
+		created sql.NullTime
+		parent  sql.NullInt64
+	)
+	if err := r.Scan(&m.ID, &m.ConversationID, &m.Role, &m.Content, &model, &created, &parent, &m.Superseded, &m.Pinned, &m.PromptTokens, &m.CompletionTokens, &m.TotalTokens, &m.Cost); err != nil {
+		return nil, err
+	}
+	m.Model = model.String
+	m.CreatedAt = created.Time
+	m.ParentID = parent.Int64
+	return &m, nil
+}
+
+// SetPinned pins or unpins a message.
+func (s *Store) SetPinned(id int64, pinned bool) error {
+	res, err := s.db.Exec(`UPDATE messages SET pinned = ? WHERE id = ?`, pinned, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
+
+// ListPinned returns the current pinned messages of a conversation, oldest first.
+func (s *Store) ListPinned(conversationID string) ([]Message, error) {
+	rows, err := s.db.Query(messageSelect+` WHERE conversation_id = ? AND pinned = 1 AND superseded = 0 ORDER BY id ASC`, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
+
+// GetMessage returns a single message by id.
+func (s *Store) GetMessage(id int64) (*Message, error) {
+	m, err := scanMessage(s.db.QueryRow(messageSelect+` WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMessageNotFound
+	}
+	return m, err
+}
+
 func (s *Store) ClearConversation(conversationID string) error {
 	_, err := s.db.Exec(`DELETE FROM messages WHERE conversation_id = ?`, conversationID)
 	if err != nil {
 		return err
 	}
-	_, err = s.db.Exec(`UPDATE conversations SET context_prompt_tokens = 0, context_answer_tokens = 0, prompt_message_count = 0, answer_message_count = 0 WHERE id = ?`, conversationID)
+	_, err = s.db.Exec(`UPDATE conversations SET context_prompt_tokens = 0, context_answer_tokens = 0, prompt_message_count = 0, answer_message_count = 0, summary = NULL, summary_through = 0 WHERE id = ?`, conversationID)
 	return err
 }
@@ -1,9 +1,12 @@
 package sqlite
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestStoreCRUD(t *testing.T) {
@@ -48,3 +51,526 @@ func TestStoreCRUD(t *testing.T) {
 		t.Fatalf("unexpected second message: %+v", msgs[1])
 	}
 }
+
+func TestConversationManagement(t *testing.T) {
+	t.Parallel()
+	st, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+
+	for _, id := range []string{"a", "b"} {
+		if _, err := st.CreateOrGetConversation(id, id); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+	if _, err := st.AppendMessage("a", "user", "hi"); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := st.RenameConversation("a", "Alpha"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+
+	convs, err := st.ListConversations()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(convs) != 2 {
+		t.Fatalf("want 2 conversations, got %d", len(convs))
+	}
+	if convs[0].ID != "a" || convs[0].Title != "Alpha" || convs[0].MessageCount != 1 {
+		t.Fatalf("unexpected first conversation: %+v", convs[0])
+	}
+	if convs[0].CreatedAt.IsZero() {
+		t.Fatalf("created_at not populated")
+	}
+
+	if ok, err := st.SetGeneratedTitle("a", "Generated"); err != nil || ok {
+		t.Fatalf("generated title must not override a user title: ok=%v err=%v", ok, err)
+	}
+	if ok, err := st.SetGeneratedTitle("b", "Generated"); err != nil || !ok {
+		t.Fatalf("set generated title: ok=%v err=%v", ok, err)
+	}
+	if conv, _ := st.GetConversation("b"); conv.Title != "Generated" || conv.TitleLocked {
+		t.Fatalf("unexpected conversation after generated title: %+v", conv)
+	}
+
+	if err := st.DeleteConversation("a"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := st.GetConversation("a"); !errors.Is(err, ErrConversationNotFound) {
+		t.Fatalf("want ErrConversationNotFound, got %v", err)
+	}
+	if msgs, _ := st.ListMessages("a", 10); len(msgs) != 0 {
+		t.Fatalf("messages not deleted: %+v", msgs)
+	}
+	if err := st.RenameConversation("missing", "x"); !errors.Is(err, ErrConversationNotFound) {
+		t.Fatalf("rename missing: want ErrConversationNotFound, got %v", err)
+	}
+}
+
+func TestMostRecentConversation(t *testing.T) {
+	t.Parallel()
+	st, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+
+	if _, err := st.MostRecentConversation(); !errors.Is(err, ErrConversationNotFound) {
+		t.Fatalf("empty store: want ErrConversationNotFound, got %v", err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if _, err := st.CreateOrGetConversation(id, id); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+	if _, err := st.AppendMessage("a", "user", "hi"); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	conv, err := st.MostRecentConversation()
+	if err != nil {
+		t.Fatalf("most recent: %v", err)
+	}
+	if conv.ID != "a" {
+		t.Fatalf("want a, got %s", conv.ID)
+	}
+	if conv.LastActiveAt.IsZero() {
+		t.Fatalf("last_active_at not populated")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+	st, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+
+	if _, err := st.CreateOrGetConversation("ops", "ops"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := st.AppendMessage("ops", "user", "how do I reload nginx.conf?"); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	id, err := st.AppendMessage("ops", "assistant", "Run nginx -s reload after editing nginx.conf.")
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if _, err := st.AppendMessage("ops", "user", "thanks"); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	hits, err := st.Search("nginx.conf reload", "", 10)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(hits) != 2 {
+		t.Fatalf("want 2 hits, got %d: %+v", len(hits), hits)
+	}
+	if !strings.Contains(hits[0].Snippet, SnippetStart+"reload"+SnippetEnd) {
+		t.Fatalf("snippet not highlighted: %q", hits[0].Snippet)
+	}
+	if hits, _ := st.Search("relo*", "", 10); len(hits) != 2 {
+		t.Fatalf("prefix query: want 2 hits, got %d", len(hits))
+	}
+
+	if err := st.ClearConversation("ops"); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	hits, err = st.Search("nginx", "", 10)
+	if err != nil {
+		t.Fatalf("search after clear: %v", err)
+	}
+	if len(hits) != 0 {
+		t.Fatalf("index not updated after delete (message %d): %+v", id, hits)
+	}
+}
+
+func TestForkConversation(t *testing.T) {
+	t.Parallel()
+	st, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+
+	if _, err := st.CreateOrGetConversation("main", "Main"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	var ids []int64
+	for _, c := range []string{"q1", "a1", "q2", "a2"} {
+		role := "user"
+		if c[0] == 'a' {
+			role = "assistant"
+		}
+		id, err := st.AppendMessage("main", role, c)
+		if err != nil {
+			t.Fatalf("append: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if m, _ := st.GetMessage(ids[2]); m.ParentID != ids[1] {
+		t.Fatalf("want parent %d, got %+v", ids[1], m)
+	}
+	if err := st.SetPersona("main", "reviewer"); err != nil {
+		t.Fatalf("set persona: %v", err)
+	}
+
+	conv, err := st.ForkConversation("alt", ids[1])
+	if err != nil {
+		t.Fatalf("fork: %v", err)
+	}
+	if conv.ForkedFrom != "main" || conv.ForkedFromMessage != ids[1] || conv.MessageCount != 2 || conv.Persona != "reviewer" {
+		t.Fatalf("unexpected fork: %+v", conv)
+	}
+	msgs, err := st.AllMessages("alt")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].Content != "q1" || msgs[1].Content != "a1" || msgs[1].ParentID != msgs[0].ID || msgs[0].ParentID != 0 {
+		t.Fatalf("unexpected fork history: %+v", msgs)
+	}
+	if _, err := st.ForkConversation("alt", ids[0]); err == nil {
+		t.Fatalf("want error forking into an existing conversation")
+	}
+	if _, err := st.ForkConversation("x", 9999); !errors.Is(err, ErrMessageNotFound) {
+		t.Fatalf("want ErrMessageNotFound, got %v", err)
+	}
+}
+
+func TestVariants(t *testing.T) {
+	t.Parallel()
+	st, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+
+	if _, err := st.CreateOrGetConversation("c", "c"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	q, _ := st.AppendMessage("c", "user", "question")
+	a1, _ := st.AppendMessage("c", "assistant", "first answer")
+
+	// retry: supersede the answer and store a new one with the same parent
+	if err := st.SupersedeFrom(a1); err != nil {
+		t.Fatalf("supersede: %v", err)
+	}
+	a2, err := st.AppendMessage("c", "assistant", "second answer")
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	msgs, _ := st.AllMessages("c")
+	if len(msgs) != 2 || msgs[1].ID != a2 || msgs[1].ParentID != q {
+		t.Fatalf("unexpected history after retry: %+v", msgs)
+	}
+	variants, err := st.ListVariants(a2)
+	if err != nil {
+		t.Fatalf("variants: %v", err)
+	}
+	if len(variants) != 2 || variants[0].ID != a1 || !variants[0].Superseded || variants[1].Superseded {
+		t.Fatalf("unexpected variants: %+v", variants)
+	}
+
+	// edit: replace the prompt, which also supersedes its answer
+	q2, err := st.ReplaceMessage(q, &Message{Role: "user", Content: "better question"})
+	if err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	msgs, _ = st.AllMessages("c")
+	if len(msgs) != 1 || msgs[0].ID != q2 || msgs[0].ParentID != 0 {
+		t.Fatalf("unexpected history after edit: %+v", msgs)
+	}
+	if conv, _ := st.GetConversation("c"); conv.MessageCount != 1 {
+		t.Fatalf("superseded messages counted: %+v", conv)
+	}
+
+	a3, _ := st.AppendMessage("c", "assistant", "answer")
+	if err := st.DeleteMessage(q2); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if m, _ := st.GetMessage(a3); m.ParentID != 0 {
+		t.Fatalf("child not re-parented: %+v", m)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	t.Parallel()
+	st, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+
+	old := time.Now().Add(-100 * 24 * time.Hour)
+	var oldID int64
+	for _, id := range []string{"stale", "mixed"} {
+		if _, err := st.CreateConversation(&Conversation{ID: id, CreatedAt: old}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if oldID, err = st.InsertMessage(&Message{ConversationID: id, Role: "user", Content: strings.Repeat("x", 4096), CreatedAt: old}); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	if _, err := st.AppendMessage("mixed", "user", "recent"); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := st.SetSummary("mixed", "the user sent xxx", oldID); err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+
+	opts := PruneOptions{Before: time.Now().Add(-90 * 24 * time.Hour), DryRun: true}
+	res, err := st.Prune(opts)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if res.Messages != 2 || res.Conversations != 1 || res.ContentBytes != 8192 {
+		t.Fatalf("unexpected dry run result: %+v", res)
+	}
+	if msgs, _ := st.AllMessages("stale"); len(msgs) != 1 {
+		t.Fatalf("dry run deleted messages")
+	}
+
+	opts.DryRun = false
+	if res, err = st.Prune(opts); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if res.Messages != 2 || res.Conversations != 1 {
+		t.Fatalf("unexpected prune result: %+v", res)
+	}
+	if _, err := st.GetConversation("stale"); !errors.Is(err, ErrConversationNotFound) {
+		t.Fatalf("stale conversation kept: %v", err)
+	}
+	msgs, _ := st.AllMessages("mixed")
+	if len(msgs) != 1 || msgs[0].Content != "recent" {
+		t.Fatalf("unexpected remaining messages: %+v", msgs)
+	}
+	if conv, _ := st.GetConversation("mixed"); conv.Summary != "" || conv.SummaryThrough != 0 {
+		t.Fatalf("summary of pruned messages kept: %+v", conv)
+	}
+	if hits, _ := st.Search("xxx*", "", 10); len(hits) != 0 {
+		t.Fatalf("pruned messages still searchable")
+	}
+}
+
+func TestPinned(t *testing.T) {
+	t.Parallel()
+	st, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+
+	if _, err := st.CreateOrGetConversation("c", "c"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	spec, _ := st.AppendMessage("c", "user", "spec: always use tabs")
+	_, _ = st.AppendMessage("c", "assistant", "ok")
+	if err := st.SetPinned(spec, true); err != nil {
+		t.Fatalf("pin: %v", err)
+	}
+	pinned, err := st.ListPinned("c")
+	if err != nil || len(pinned) != 1 || pinned[0].ID != spec || !pinned[0].Pinned {
+		t.Fatalf("unexpected pinned: %+v (%v)", pinned, err)
+	}
+	fork, err := st.ForkConversation("f", spec)
+	if err != nil {
+		t.Fatalf("fork: %v", err)
+	}
+	if p, _ := st.ListPinned(fork.ID); len(p) != 1 {
+		t.Fatalf("pin not carried into fork: %+v", p)
+	}
+	if err := st.SetPinned(spec, false); err != nil {
+		t.Fatalf("unpin: %v", err)
+	}
+	if p, _ := st.ListPinned("c"); len(p) != 0 {
+		t.Fatalf("still pinned: %+v", p)
+	}
+	if err := st.SetPinned(9999, true); !errors.Is(err, ErrMessageNotFound) {
+		t.Fatalf("want ErrMessageNotFound, got %v", err)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	t.Parallel()
+	st, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+
+	for _, id := range []string{"a", "b"} {
+		if _, err := st.CreateOrGetConversation(id, id); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	old := time.Now().Add(-10 * 24 * time.Hour)
+	answers := []Message{
+		{ConversationID: "a", Model: "big", PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110, Cost: 0.5},
+		{ConversationID: "a", Model: "small", PromptTokens: 50, CompletionTokens: 5, TotalTokens: 55, Cost: 0.01},
+		{ConversationID: "b", Model: "big", PromptTokens: 200, CompletionTokens: 20, TotalTokens: 220, Cost: 1},
+		{ConversationID: "b", Model: "big", PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2, Cost: 9, CreatedAt: old},
+		{ConversationID: "b", Model: "imported"}, // no usage
+	}
+	for i := range answers {
+		answers[i].Role = "assistant"
+		answers[i].Content = "answer"
+		if _, err := st.InsertMessage(&answers[i]); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	// Replaced answers were still paid for
+	if err := st.SupersedeFrom(3); err != nil {
+		t.Fatalf("supersede: %v", err)
+	}
+
+	week := time.Now().Add(-7 * 24 * time.Hour)
+	rows, err := st.Usage(UsageOptions{Since: week, By: "model"})
+	if err != nil {
+		t.Fatalf("usage: %v", err)
+	}
+	want := []UsageRow{
+		{Key: "big", Requests: 2, PromptTokens: 300, CompletionTokens: 30, TotalTokens: 330, Cost: 1.5},
+		{Key: "small", Requests: 1, PromptTokens: 50, CompletionTokens: 5, TotalTokens: 55, Cost: 0.01},
+	}
+	if len(rows) != len(want) || rows[0] != want[0] || rows[1] != want[1] {
+		t.Fatalf("by model = %+v", rows)
+	}
+	rows, _ = st.Usage(UsageOptions{By: "conversation", ConversationID: "b"})
+	if len(rows) != 1 || rows[0].Key != "b" || rows[0].Requests != 2 || rows[0].Cost != 10 {
+		t.Fatalf("by conversation = %+v", rows)
+	}
+	rows, _ = st.Usage(UsageOptions{By: "day"})
+	if len(rows) != 2 || rows[0].Key != old.UTC().Format("2006-01-02") {
+		t.Fatalf("by day = %+v", rows)
+	}
+	if _, err := st.Usage(UsageOptions{By: "week"}); err == nil {
+		t.Fatal("expected an error for an unknown grouping")
+	}
+
+	// Title and summary requests count too, and usage outlives the conversation's messages
+	if err := st.RecordUsage(&UsageRecord{ConversationID: "b", Kind: UsageTitle, Model: "small", PromptTokens: 30, CompletionTokens: 3, TotalTokens: 33, Cost: 0.02}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if err := st.DeleteConversation("b"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	rows, _ = st.Usage(UsageOptions{By: "kind", ConversationID: "b"})
+	if len(rows) != 2 || rows[0] != (UsageRow{Key: UsageAnswer, Requests: 2, PromptTokens: 201, CompletionTokens: 21, TotalTokens: 222, Cost: 10}) ||
+		rows[1] != (UsageRow{Key: UsageTitle, Requests: 1, PromptTokens: 30, CompletionTokens: 3, TotalTokens: 33, Cost: 0.02}) {
+		t.Fatalf("by kind after delete = %+v", rows)
+	}
+}
+
+func TestUsageMigration(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "test.db")
+	st, err := Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := st.CreateOrGetConversation("a", "a"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	for _, m := range []Message{
+		{ConversationID: "a", Role: "user", Content: "q"},
+		{ConversationID: "a", Role: "assistant", Content: "answer", Model: "big", PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110, Cost: 0.5},
+		{ConversationID: "a", Role: "assistant", Content: "imported"},
+	} {
+		if _, err := st.InsertMessage(&m); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	// A database from before the usage table only has usage on its answers
+	if _, err := st.db.Exec(`DROP TABLE usage`); err != nil {
+		t.Fatalf("drop: %v", err)
+	}
+	st.Close()
+	if st, err = Open(path); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer st.Close()
+	rows, err := st.Usage(UsageOptions{By: "model"})
+	if err != nil || len(rows) != 1 || rows[0] != (UsageRow{Key: "big", Requests: 1, PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110, Cost: 0.5}) {
+		t.Fatalf("migrated usage = %+v, %v", rows, err)
+	}
+}
+
+func TestSimilarMessages(t *testing.T) {
+	t.Parallel()
+	st, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+
+	for _, id := range []string{"old", "cur"} {
+		if _, err := st.CreateOrGetConversation(id, id); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	near, _ := st.AppendMessage("old", "user", "near")
+	far, _ := st.AppendMessage("old", "assistant", "far")
+	own, _ := st.AppendMessage("cur", "user", "own")
+	pending, err := st.UnembeddedMessages("emb", 10)
+	if err != nil || len(pending) != 3 || pending[0].ID != own {
+		t.Fatalf("unembedded = %+v (%v)", pending, err)
+	}
+	vectors := map[int64][]float32{near: {2, 0.2}, far: {0, 1}, own: {1, 0}}
+	for id, v := range vectors {
+		if err := st.SaveEmbedding(id, "emb", v); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+	if p, _ := st.UnembeddedMessages("emb", 10); len(p) != 0 {
+		t.Fatalf("still unembedded: %+v", p)
+	}
+
+	hits, err := st.SimilarMessages([]float32{1, 0}, SimilarOptions{Model: "emb", K: 5, MinScore: 0.5, ExcludeConversation: "cur"})
+	if err != nil {
+		t.Fatalf("similar: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ID != near || hits[0].Score < 0.99 || hits[0].ConversationTitle != "old" {
+		t.Fatalf("hits = %+v", hits)
+	}
+	if hits, _ := st.SimilarMessages([]float32{1, 0}, SimilarOptions{Model: "emb", K: 5, MinScore: -1, ExcludeConversation: "cur"}); len(hits) != 2 || hits[1].ID != far {
+		t.Fatalf("hits without threshold = %+v", hits)
+	}
+	// Only the newest message's vector is within a window of one
+	if hits, _ := st.SimilarMessages([]float32{1, 0}, SimilarOptions{Model: "emb", K: 5, MinScore: -1, Window: 1}); len(hits) != 1 || hits[0].ID != own {
+		t.Fatalf("hits in window = %+v", hits)
+	}
+
+	// Vectors go with their messages
+	if err := st.DeleteConversation("old"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if hits, _ := st.SimilarMessages([]float32{1, 0}, SimilarOptions{Model: "emb", K: 5, MinScore: -1}); len(hits) != 1 || hits[0].ID != own {
+		t.Fatalf("hits after delete = %+v", hits)
+	}
+	if err := st.SetRecall("cur", false); err != nil {
+		t.Fatalf("set recall: %v", err)
+	}
+	if c, _ := st.GetConversation("cur"); c.Recall {
+		t.Fatal("recall still on")
+	}
+	if err := st.SetRecall("nope", false); !errors.Is(err, ErrConversationNotFound) {
+		t.Fatalf("set recall on missing conversation: %v", err)
+	}
+}
+
+func TestOpenSetsBusyTimeout(t *testing.T) {
+	t.Parallel()
+	st, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+	var ms int
+	if err := st.db.QueryRow(`PRAGMA busy_timeout`).Scan(&ms); err != nil || ms != 5000 {
+		t.Fatalf("busy_timeout = %d, %v", ms, err)
+	}
+}
@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+)
+
+// Kinds of requests recorded in the usage table.
+const (
+	UsageAnswer  = "answer"
+	UsageTitle   = "title"
+	UsageSummary = "summary"
+)
+
+// UsageRecord is the token usage and cost of one provider request. Records are kept when the
+// messages of their conversation are pruned, cleared or deleted, since the request was paid for.
+type UsageRecord struct {
+	ConversationID string
+	// MessageID is the answer the request produced; zero for title and summary requests.
+	MessageID int64
+	// Kind is UsageAnswer, UsageTitle or UsageSummary.
+	Kind             string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	// Cost is the price in USD; zero when the model has no known pricing.
+	Cost float64
+	// CreatedAt is when the request was made; zero means now.
+	CreatedAt time.Time
+}
+
+// initUsage creates the usage table. A new table is filled from the usage stored with
+// answers, which was the only record before it existed.
+func (s *Store) initUsage() error {
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'usage'`).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	_, err := s.db.Exec(`CREATE TABLE usage (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		conversation_id TEXT NOT NULL,
+		message_id INTEGER,
+		kind TEXT NOT NULL,
+		model TEXT,
+		prompt_tokens INTEGER DEFAULT 0,
+		completion_tokens INTEGER DEFAULT 0,
+		total_tokens INTEGER DEFAULT 0,
+		cost REAL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX idx_usage_created_at ON usage(created_at);
+	INSERT INTO usage(conversation_id, message_id, kind, model, prompt_tokens, completion_tokens, total_tokens, cost, created_at)
+		SELECT conversation_id, id, 'answer', model, prompt_tokens, completion_tokens, total_tokens, cost, created_at
+		FROM messages WHERE role = 'assistant' AND total_tokens > 0 ORDER BY id;`)
+	return err
+}
+
+// RecordUsage stores the usage of a provider request.
+func (s *Store) RecordUsage(u *UsageRecord) error {
+	var created any
+	if !u.CreatedAt.IsZero() {
+		created = u.CreatedAt.UTC().Format(timeLayout)
+	}
+	_, err := s.db.Exec(`INSERT INTO usage(conversation_id, message_id, kind, model, prompt_tokens, completion_tokens, total_tokens, cost, created_at)
+		VALUES(?, NULLIF(?, 0), ?, NULLIF(?, ''), ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP))`,
+		u.ConversationID, u.MessageID, u.Kind, u.Model, u.PromptTokens, u.CompletionTokens, u.TotalTokens, u.Cost, created)
+	return err
+}
+
+// UsageOptions selects the requests summed by Usage.
+type UsageOptions struct {
+	// Since only counts requests made at or after this time; zero means all time.
+	Since time.Time
+	// ConversationID limits the report to one conversation; empty means all.
+	ConversationID string
+	// By groups rows by "model", "day" (UTC), "conversation" or "kind".
+	By string
+}
+
+// UsageRow is the token usage and cost of the requests sharing one Key.
+type UsageRow struct {
+	Key              string
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	Cost             float64
+}
+
+var usageGroups = map[string]string{
+	"model":        `COALESCE(model, '')`,
+	"day":          `date(created_at)`,
+	"conversation": `conversation_id`,
+	"kind":         `kind`,
+}
+
+// Usage sums token usage and cost of provider requests, largest cost first (by day: oldest
+// first). Answers replaced by /retry or /edit and requests of deleted conversations are
+// included, since they were paid for; imported answers carry no usage and are left out.
+func (s *Store) Usage(opts UsageOptions) ([]UsageRow, error) {
+	group, ok := usageGroups[opts.By]
+	if !ok {
+		return nil, fmt.Errorf("unknown grouping %q (want model, day, conversation or kind)", opts.By)
+	}
+	order := `SUM(cost) DESC, key`
+	if opts.By == "day" {
+		order = `key`
+	}
+	var since string
+	if !opts.Since.IsZero() {
+		since = opts.Since.UTC().Format(timeLayout)
+	}
+	rows, err := s.db.Query(`SELECT `+group+` AS key, COUNT(*), SUM(prompt_tokens), SUM(completion_tokens), SUM(total_tokens), SUM(cost)
+		FROM usage WHERE created_at >= ? AND (? = '' OR conversation_id = ?)
+		GROUP BY key ORDER BY `+order, since, opts.ConversationID, opts.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []UsageRow
+	for rows.Next() {
+		var r UsageRow
+		if err := rows.Scan(&r.Key, &r.Requests, &r.PromptTokens, &r.CompletionTokens, &r.TotalTokens, &r.Cost); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,67 @@
+package sqlite
+
+// SupersedeFrom marks messageID and every later current message of its conversation as
+// superseded. They stay in the database as variants but no longer appear in history.
+func (s *Store) SupersedeFrom(messageID int64) error {
+	m, err := s.GetMessage(messageID)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`UPDATE messages SET superseded = 1 WHERE conversation_id = ? AND id >= ? AND superseded = 0`, m.ConversationID, messageID)
+	return err
+}
+
+// ReplaceMessage supersedes messageID and everything after it, then stores m in its place
+// with the same parent. It returns the id of the new message.
+func (s *Store) ReplaceMessage(messageID int64, m *Message) (int64, error) {
+	old, err := s.GetMessage(messageID)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.SupersedeFrom(messageID); err != nil {
+		return 0, err
+	}
+	repl := *m
+	repl.ConversationID = old.ConversationID
+	repl.ParentID = old.ParentID
+	return s.InsertMessage(&repl)
+}
+
+// DeleteMessage permanently removes a single message. Messages that followed it are re-parented
+// to its parent so history stays connected.
+func (s *Store) DeleteMessage(messageID int64) error {
+	m, err := s.GetMessage(messageID)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var parent any
+	if m.ParentID != 0 {
+		parent = m.ParentID
+	}
+	if _, err := tx.Exec(`UPDATE messages SET parent_id = ? WHERE parent_id = ?`, parent, messageID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM messages WHERE id = ?`, messageID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListVariants returns the variants of messageID: messages in the same conversation with the
+// same parent and role, including superseded ones, oldest first.
+func (s *Store) ListVariants(messageID int64) ([]Message, error) {
+	m, err := s.GetMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(messageSelect+` WHERE conversation_id = ? AND role = ? AND COALESCE(parent_id, 0) = ? ORDER BY id ASC`, m.ConversationID, m.Role, m.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
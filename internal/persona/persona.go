@@ -0,0 +1,98 @@
+// Package persona loads personas: named bundles of a system prompt, sampling parameters, a
+// preferred model and few-shot examples that a conversation can be attached to.
+//
+// Each persona is a JSON file in PERSONAS_DIR (default "personas") named after the persona,
+// e.g. personas/reviewer.json:
+//
+//	{
+//	  "description": "terse code reviewer",
+//	  "system_prompt": "You review Go code. Point out bugs first.",
+//	  "model": "gpt-4o",
+//	  "temperature": 0.2,
+//	  "top_p": 0.9,
+//	  "examples": [{"user": "...", "assistant": "..."}]
+//	}
+//
+// Every field is optional. When a conversation is answered, each field is taken from its
+// persona if set there, else from the global configuration (SYSTEM_PROMPT, the active model,
+// TEMPERATURE, TOP_P); a conversation whose persona file has been removed uses the global
+// configuration only.
+package persona
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Persona bundles what a conversation sends besides its history: a system prompt, sampling
+// parameters, a preferred model and few-shot example turns. Empty fields fall back to the
+// global configuration.
+type Persona struct {
+	Name         string    `json:"-"`
+	Description  string    `json:"description,omitempty"`
+	SystemPrompt string    `json:"system_prompt,omitempty"`
+	Model        string    `json:"model,omitempty"`
+	Temperature  *float64  `json:"temperature,omitempty"`
+	TopP         *float64  `json:"top_p,omitempty"`
+	Examples     []Example `json:"examples,omitempty"`
+}
+
+// Example is a few-shot turn sent before the conversation's history.
+type Example struct {
+	User      string `json:"user"`
+	Assistant string `json:"assistant"`
+}
+
+// Set maps persona names to personas.
+type Set map[string]Persona
+
+// Names returns the persona names in alphabetical order.
+func (s Set) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load reads every *.json file in dir as a persona named after the file, e.g. reviewer.json
+// defines "reviewer". A missing directory or an empty path yields an empty set.
+func Load(dir string) (Set, error) {
+	set := Set{}
+	if dir == "" {
+		return set, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("personas: %w", err)
+	}
+	if len(paths) == 0 {
+		if _, err := os.Stat(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("personas: %w", err)
+		}
+	}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("personas: %w", err)
+		}
+		var p Persona
+		if err := json.Unmarshal(b, &p); err != nil {
+			return nil, fmt.Errorf("personas: %s: %w", path, err)
+		}
+		for i, ex := range p.Examples {
+			if strings.TrimSpace(ex.User) == "" || strings.TrimSpace(ex.Assistant) == "" {
+				return nil, fmt.Errorf("personas: %s: example %d needs both user and assistant text", path, i+1)
+			}
+		}
+		p.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+		set[p.Name] = p
+	}
+	return set, nil
+}
@@ -0,0 +1,42 @@
+package persona
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("reviewer.json", `{"system_prompt": "Review code.", "model": "gpt-4o", "temperature": 0,
+		"examples": [{"user": "x := 1", "assistant": "Fine."}]}`)
+	write("translator.json", `{"system_prompt": "Translate to French."}`)
+	write("notes.txt", `not a persona`)
+
+	set, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := set.Names(); !reflect.DeepEqual(got, []string{"reviewer", "translator"}) {
+		t.Fatalf("names = %v", got)
+	}
+	r := set["reviewer"]
+	if r.Name != "reviewer" || r.Model != "gpt-4o" || r.Temperature == nil || *r.Temperature != 0 || r.TopP != nil || len(r.Examples) != 1 {
+		t.Fatalf("reviewer = %+v", r)
+	}
+
+	write("broken.json", `{"examples": [{"user": "hi"}]}`)
+	if _, err := Load(dir); err == nil {
+		t.Fatal("expected an error for an example without an answer")
+	}
+	if set, err := Load(filepath.Join(dir, "missing")); err != nil || len(set) != 0 {
+		t.Fatalf("missing dir: %v, %v", set, err)
+	}
+}
@@ -0,0 +1,48 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Price is what a model charges, in USD per token.
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// Cost returns the price of a request with the given token usage.
+func (p Price) Cost(promptTokens, completionTokens int) float64 {
+	return float64(promptTokens)*p.Input + float64(completionTokens)*p.Output
+}
+
+// Table maps model names to prices.
+type Table map[string]Price
+
+// Load reads a pricing file: a JSON object mapping model names to USD per million input and
+// output tokens, e.g. {"gpt-4o": {"input": 2.5, "output": 10}}. An empty path yields an empty table.
+func Load(path string) (Table, error) {
+	if path == "" {
+		return Table{}, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("pricing: %w", err)
+	}
+	var raw map[string]struct {
+		Input  *float64 `json:"input"`
+		Output *float64 `json:"output"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("pricing: %s: %w", path, err)
+	}
+	t := make(Table, len(raw))
+	for model, p := range raw {
+		if p.Input == nil || p.Output == nil {
+			return nil, fmt.Errorf("pricing: %s: %q needs both input and output prices", path, model)
+		}
+		t[model] = Price{Input: *p.Input / 1e6, Output: *p.Output / 1e6}
+	}
+	return t, nil
+}
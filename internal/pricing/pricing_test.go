@@ -0,0 +1,33 @@
+package pricing
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.json")
+	if err := os.WriteFile(path, []byte(`{"gpt-4o": {"input": 2.5, "output": 10}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tab, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	// 1000 prompt tokens at $2.50/M + 500 completion tokens at $10/M
+	if got := tab["gpt-4o"].Cost(1000, 500); math.Abs(got-0.0075) > 1e-12 {
+		t.Fatalf("cost = %v", got)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"m": {"input": 1}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected an error for a missing output price")
+	}
+	if tab, err := Load(""); err != nil || len(tab) != 0 {
+		t.Fatalf("empty path: %v, %v", tab, err)
+	}
+}
@@ -0,0 +1,303 @@
+package anthropic
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/yourname/clichat/internal/provider"
+)
+
+// APIVersion is the anthropic-version header sent with every request.
+const APIVersion = "2023-06-01"
+
+// DefaultBaseURL is the Anthropic API endpoint.
+const DefaultBaseURL = "https://api.anthropic.com"
+
+// Client is an Anthropic Messages API client.
+type Client struct {
+	BaseURL string
+	APIKey  string
+	// MaxTokens is the max_tokens sent with every request; the API requires it.
+	MaxTokens int
+	// ContextTokens overrides the context window ModelInfo reports (MODEL_CONTEXT_TOKENS).
+	ContextTokens int
+	HTTP          *provider.Transport
+}
+
+func NewClient(baseURL, apiKey string, maxTokens int) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), APIKey: apiKey, MaxTokens: maxTokens, HTTP: provider.NewTransport(provider.DefaultTimeouts, provider.DefaultMaxRetries)}
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type tool struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+type messagesRequest struct {
+	Model       string    `json:"model"`
+	MaxTokens   int       `json:"max_tokens"`
+	System      string    `json:"system,omitempty"`
+	Messages    []message `json:"messages"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
+	Stream      bool      `json:"stream"`
+	Tools       []tool    `json:"tools,omitempty"`
+}
+
+// newMessagesRequest maps a chat request to the Messages API: system messages move to the
+// top-level system field, and tools are translated to their Anthropic server tools.
+func (c *Client) newMessagesRequest(req provider.ChatRequest) messagesRequest {
+	out := messagesRequest{Model: req.Model, MaxTokens: c.MaxTokens, Stream: req.Stream}
+	var system []string
+	for _, m := range req.Messages {
+		switch {
+		case m.Content == "":
+			// The API rejects empty text blocks
+		case m.Role == "system":
+			system = append(system, m.Content)
+		default:
+			out.Messages = append(out.Messages, message{Role: m.Role, Content: m.Content})
+		}
+	}
+	out.System = strings.Join(system, "\n\n")
+	out.Temperature, out.TopP = req.Temperature, req.TopP
+	if out.Temperature != nil && out.TopP != nil && !acceptsTemperatureWithTopP(req.Model) {
+		out.TopP = nil
+	}
+	for _, t := range req.Tools {
+		if t.Type == "web_search" {
+			out.Tools = append(out.Tools, tool{Type: "web_search_20250305", Name: "web_search"})
+		}
+	}
+	return out
+}
+
+// acceptsTemperatureWithTopP reports whether model accepts temperature and top_p in the same
+// request. Models since Claude Opus 4.1 reject that, so unknown models are assumed to as well
+// and get only the temperature.
+func acceptsTemperatureWithTopP(model string) bool {
+	for _, prefix := range []string{"claude-instant", "claude-2", "claude-3", "claude-opus-4-0", "claude-sonnet-4-0", "claude-opus-4-2025", "claude-sonnet-4-2025"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// EncodeRequest returns the Messages API body StreamChatUsage sends for req.
+func (c *Client) EncodeRequest(_ context.Context, req provider.ChatRequest) ([]byte, error) {
+	return json.Marshal(c.newMessagesRequest(req))
+}
+
+// StreamChatUsage starts a streaming message. See provider.Provider for the channel semantics.
+func (c *Client) StreamChatUsage(ctx context.Context, reqPayload provider.ChatRequest) (<-chan string, <-chan provider.Usage, <-chan error) {
+	deltas := make(chan string)
+	usage := make(chan provider.Usage, 1)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(deltas)
+		defer close(errs)
+		var (
+			u   provider.Usage
+			got bool
+		)
+		defer func() {
+			if got {
+				u.TotalTokens = u.PromptTokens + u.CompletionTokens
+				usage <- u
+			}
+			close(usage)
+		}()
+
+		bodyBytes, err := c.EncodeRequest(ctx, reqPayload)
+		if err != nil {
+			errs <- err
+			return
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/v1/messages", strings.NewReader(string(bodyBytes)))
+		if err != nil {
+			errs <- err
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		c.setHeaders(req)
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			errs <- responseError(resp)
+			return
+		}
+
+		// Events look like "event: content_block_delta" followed by "data: {...}"; the data
+		// repeats the event type, so the event lines are not needed.
+		reader := bufio.NewReader(resp.Body)
+		for {
+			line, err := reader.ReadString('\n')
+			if data, ok := strings.CutPrefix(strings.TrimSpace(line), "data:"); ok {
+				var ev streamEvent
+				if json.Unmarshal([]byte(strings.TrimSpace(data)), &ev) == nil {
+					switch ev.Type {
+					case "message_start":
+						got = true
+						in := ev.Message.Usage
+						u.PromptTokens = in.InputTokens + in.CacheCreationInputTokens + in.CacheReadInputTokens
+						u.CompletionTokens = in.OutputTokens
+					case "content_block_delta":
+						if ev.Delta.Type == "text_delta" && ev.Delta.Text != "" {
+							deltas <- ev.Delta.Text
+						}
+					case "message_delta":
+						if ev.Usage != nil {
+							got = true
+							// output_tokens is cumulative
+							u.CompletionTokens = ev.Usage.OutputTokens
+						}
+					case "message_stop":
+						return
+					case "error":
+						errs <- provider.Classify(&provider.APIError{Provider: "anthropic", Type: ev.Error.Type, Message: ev.Error.Message})
+						return
+					}
+				}
+			}
+			if err != nil {
+				if err == io.EOF {
+					return
+				}
+				errs <- err
+				return
+			}
+		}
+	}()
+	return deltas, usage, errs
+}
+
+type apiUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+}
+
+type apiError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type streamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage apiUsage `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Usage *apiUsage `json:"usage"`
+	Error apiError  `json:"error"`
+}
+
+// ListModels fetches the available models, following pagination.
+func (c *Client) ListModels(ctx context.Context) ([]provider.Model, error) {
+	var (
+		models []provider.Model
+		after  string
+	)
+	for {
+		q := url.Values{"limit": {"1000"}}
+		if after != "" {
+			q.Set("after_id", after)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/v1/models?"+q.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		c.setHeaders(req)
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var out struct {
+			Data []struct {
+				ID string `json:"id"`
+			} `json:"data"`
+			HasMore bool   `json:"has_more"`
+			LastID  string `json:"last_id"`
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			err = responseError(resp)
+		} else {
+			err = json.NewDecoder(resp.Body).Decode(&out)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("list models: %w", err)
+		}
+		for _, m := range out.Data {
+			// The display name is not a valid model id, so only the id is reported
+			models = append(models, provider.Model{ID: m.ID})
+		}
+		if !out.HasMore || out.LastID == "" {
+			return models, nil
+		}
+		after = out.LastID
+	}
+}
+
+// contextWindows maps model id prefixes to input limits, which the Models API does not report.
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"claude-opus-4", 200000},
+	{"claude-sonnet-4", 200000},
+	{"claude-haiku-4", 200000},
+	{"claude-3", 200000},
+	{"claude-2.1", 200000},
+	{"claude-2", 100000},
+	{"claude-instant", 100000},
+}
+
+// ModelInfo reports the context window of model: ContextTokens when set, else the window of a
+// known Claude model. Sampling parameters and prices are not known.
+func (c *Client) ModelInfo(ctx context.Context, model string) (provider.ModelInfo, bool) {
+	if c.ContextTokens > 0 {
+		return provider.ModelInfo{Name: model, MaxInputTokens: c.ContextTokens}, true
+	}
+	for _, w := range contextWindows {
+		if strings.HasPrefix(model, w.prefix) {
+			return provider.ModelInfo{Name: model, MaxInputTokens: w.tokens}, true
+		}
+	}
+	return provider.ModelInfo{}, false
+}
+
+func (c *Client) setHeaders(req *http.Request) {
+	req.Header.Set("anthropic-version", APIVersion)
+	if c.APIKey != "" {
+		req.Header.Set("x-api-key", c.APIKey)
+	}
+}
+
+// responseError turns an error response into a typed provider error.
+func responseError(resp *http.Response) error {
+	return provider.ResponseError("anthropic", resp)
+}
@@ -0,0 +1,171 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yourname/clichat/internal/provider"
+)
+
+func TestStreamChat(t *testing.T) {
+	var got messagesRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/messages", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "key" || r.Header.Get("anthropic-version") != APIVersion {
+			t.Errorf("missing headers: %v", r.Header)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range []string{
+			`event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","role":"assistant","content":[],"usage":{"input_tokens":20,"cache_read_input_tokens":5,"output_tokens":1}}}`,
+			`event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`event: ping
+data: {"type":"ping"}`,
+			`event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}`,
+			`event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}`,
+			`event: content_block_stop
+data: {"type":"content_block_stop","index":0}`,
+			`event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":7}}`,
+			`event: message_stop
+data: {"type":"message_stop"}`,
+		} {
+			fmt.Fprint(w, ev+"\n\n")
+		}
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	c := NewClient(ts.URL, "key", 1000)
+	temp, topP := 0.2, 0.9
+	deltas, usage, errs := c.StreamChatUsage(context.Background(), provider.ChatRequest{
+		Model: "claude-x",
+		Messages: []provider.ChatMessage{
+			{Role: "system", Content: "Be brief."},
+			{Role: "system", Content: "Summary: none."},
+			{Role: "user", Content: "hi"},
+		},
+		Temperature: &temp, TopP: &topP, Stream: true,
+		Tools: []provider.Tool{{Type: "web_search"}},
+	})
+	var sb strings.Builder
+	for d := range deltas {
+		sb.WriteString(d)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if sb.String() != "Hello" {
+		t.Fatalf("got %q", sb.String())
+	}
+	if got.System != "Be brief.\n\nSummary: none." || len(got.Messages) != 1 || got.Messages[0].Role != "user" || got.MaxTokens != 1000 || !got.Stream {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+	if got.Temperature == nil || *got.Temperature != 0.2 || got.TopP != nil {
+		t.Fatalf("sampling params: temperature %v, top_p %v", got.Temperature, got.TopP)
+	}
+	if len(got.Tools) != 1 || got.Tools[0].Name != "web_search" {
+		t.Fatalf("tools: %+v", got.Tools)
+	}
+	u, ok := <-usage
+	if !ok || u != (provider.Usage{PromptTokens: 25, CompletionTokens: 7, TotalTokens: 32}) {
+		t.Fatalf("usage = %+v, %v", u, ok)
+	}
+}
+
+func TestStreamChatErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/messages", func(w http.ResponseWriter, r *http.Request) {
+		var req messagesRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "too-long" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`)
+			return
+		}
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\n")
+		fmt.Fprint(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	c := NewClient(ts.URL, "key", 1000)
+
+	stream := func(model string) (string, error) {
+		deltas, _, errs := c.StreamChatUsage(context.Background(), provider.ChatRequest{Model: model, Messages: []provider.ChatMessage{{Role: "user", Content: "hi"}}, Stream: true})
+		var sb strings.Builder
+		for d := range deltas {
+			sb.WriteString(d)
+		}
+		return sb.String(), <-errs
+	}
+	if _, err := stream("too-long"); err == nil || !provider.IsContextLengthExceeded(err) {
+		t.Fatalf("want a context length error, got %v", err)
+	}
+	if text, err := stream("busy"); text != "Hi" || err == nil || !strings.Contains(err.Error(), "overloaded_error") {
+		t.Fatalf("want partial text and the stream error, got %q, %v", text, err)
+	}
+}
+
+func TestListModels(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/models", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("after_id") == "" {
+			fmt.Fprint(w, `{"data":[{"type":"model","id":"claude-a","display_name":"Claude A"}],"has_more":true,"first_id":"claude-a","last_id":"claude-a"}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":[{"type":"model","id":"claude-b","display_name":"Claude B"}],"has_more":false,"first_id":"claude-b","last_id":"claude-b"}`)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	mods, err := NewClient(ts.URL, "key", 1000).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if len(mods) != 2 || mods[0].ID != "claude-a" || mods[1].ID != "claude-b" {
+		t.Fatalf("unexpected models: %+v", mods)
+	}
+}
+
+func TestSamplingParams(t *testing.T) {
+	zero, half := 0.0, 0.5
+	c := NewClient("", "key", 1000)
+	for _, tc := range []struct {
+		model         string
+		temp, topP    *float64
+		wantT, wantTP bool
+	}{
+		{"claude-sonnet-4-5", &zero, nil, true, false},
+		{"claude-sonnet-4-5", &zero, &half, true, false},
+		{"claude-sonnet-4-5", nil, &half, false, true},
+		{"claude-3-5-haiku-20241022", &zero, &half, true, true},
+		{"claude-sonnet-4-20250514", &zero, &half, true, true},
+		{"claude-opus-4-1-20250805", nil, nil, false, false},
+	} {
+		got := c.newMessagesRequest(provider.ChatRequest{Model: tc.model, Temperature: tc.temp, TopP: tc.topP})
+		if (got.Temperature != nil) != tc.wantT || (got.TopP != nil) != tc.wantTP {
+			t.Errorf("%s (temperature %v, top_p %v): sent temperature %v, top_p %v", tc.model, tc.temp != nil, tc.topP != nil, got.Temperature != nil, got.TopP != nil)
+		}
+	}
+}
+
+func TestModelInfo(t *testing.T) {
+	c := NewClient("", "key", 1000)
+	if info, ok := c.ModelInfo(context.Background(), "claude-opus-4-1-20250805"); !ok || info.MaxInputTokens != 200000 {
+		t.Fatalf("known model: %+v, %v", info, ok)
+	}
+	if info, ok := c.ModelInfo(context.Background(), "claude-unknown"); ok {
+		t.Fatalf("unknown model reported: %+v", info)
+	}
+	c.ContextTokens = 1000000
+	if info, ok := c.ModelInfo(context.Background(), "claude-sonnet-4-5"); !ok || info.MaxInputTokens != 1000000 {
+		t.Fatalf("override not applied: %+v, %v", info, ok)
+	}
+}
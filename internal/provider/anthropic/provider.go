@@ -0,0 +1,25 @@
+package anthropic
+
+import (
+	"errors"
+
+	"github.com/yourname/clichat/internal/config"
+	"github.com/yourname/clichat/internal/provider"
+)
+
+func init() {
+	provider.Register("anthropic", func(cfg *config.Config) (provider.Provider, error) {
+		if cfg.AnthropicAPIKey == "" {
+			return nil, errors.New("anthropic: set ANTHROPIC_API_KEY")
+		}
+		c := NewClient(cfg.AnthropicBaseURL, cfg.AnthropicAPIKey, cfg.AnthropicMaxTokens)
+		c.ContextTokens = cfg.ModelContextTokens
+		c.HTTP = provider.TransportFor(cfg)
+		return c, nil
+	})
+}
+
+var (
+	_ provider.Provider       = (*Client)(nil)
+	_ provider.RequestEncoder = (*Client)(nil)
+)
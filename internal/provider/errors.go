@@ -0,0 +1,189 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is an error reported by a provider, either as an error response or in a stream.
+// Errors the chat can act on are returned as one of the more specific types below, which all
+// unwrap to their APIError.
+type APIError struct {
+	// Provider is the name of the backend, e.g. "litellm".
+	Provider string
+	// StatusCode is the HTTP status, or 0 for an error sent in the middle of a stream.
+	StatusCode int
+	// Type and Code are the error's type and code as far as the provider sends them.
+	Type    string
+	Code    string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(e.Provider)
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&sb, ": %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	if e.Type != "" {
+		sb.WriteString(": " + e.Type)
+	}
+	if e.Message != "" {
+		sb.WriteString(": " + e.Message)
+	}
+	return sb.String()
+}
+
+// AuthError reports a missing, invalid or insufficient API key.
+type AuthError struct{ *APIError }
+
+// RateLimitError reports a request refused for exceeding a rate limit or quota.
+type RateLimitError struct {
+	*APIError
+	// RetryAfter is how long the provider asked to wait, if it said so.
+	RetryAfter time.Duration
+}
+
+// ContextLengthError reports a request too large for the model's context window.
+type ContextLengthError struct{ *APIError }
+
+// ModelNotFoundError reports a model the provider does not know.
+type ModelNotFoundError struct {
+	*APIError
+	// Model is the requested model when known; the provider's message usually names it too.
+	Model string
+}
+
+// ContentFilterError reports a prompt or answer blocked by a content filter.
+type ContentFilterError struct{ *APIError }
+
+// ServerError reports a failure or overload on the provider's side.
+type ServerError struct{ *APIError }
+
+func (e *AuthError) Unwrap() error          { return e.APIError }
+func (e *RateLimitError) Unwrap() error     { return e.APIError }
+func (e *ContextLengthError) Unwrap() error { return e.APIError }
+func (e *ModelNotFoundError) Unwrap() error { return e.APIError }
+func (e *ContentFilterError) Unwrap() error { return e.APIError }
+func (e *ServerError) Unwrap() error        { return e.APIError }
+
+// contextLengthMarkers are fragments of the errors OpenAI-compatible backends return when a
+// request does not fit the model's context window.
+var contextLengthMarkers = []string{
+	"context_length_exceeded",
+	"contextwindowexceedederror",
+	"maximum context length",
+	"prompt is too long",
+	"input is too long",
+}
+
+var (
+	authMarkers          = []string{"authentication_error", "permission_error", "invalid_api_key", "authenticationerror"}
+	rateLimitMarkers     = []string{"rate_limit", "ratelimiterror", "insufficient_quota"}
+	modelNotFoundMarkers = []string{"model_not_found", "invalid model name", "notfounderror"}
+	contentFilterMarkers = []string{"content_filter", "content_policy_violation", "contentpolicyviolationerror", "content management policy"}
+	serverMarkers        = []string{"api_error", "server_error", "overloaded_error", "internalservererror", "serviceunavailableerror"}
+)
+
+// Classify returns e as the most specific error type its status, type, code and message
+// match, or e itself if none does.
+func Classify(e *APIError) error {
+	text := strings.ToLower(e.Type + " " + e.Code + " " + e.Message)
+	has := func(markers []string) bool {
+		for _, m := range markers {
+			if strings.Contains(text, m) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	// Checked first: proxies report these with a generic status, often 400
+	case has(contextLengthMarkers):
+		return &ContextLengthError{e}
+	case has(contentFilterMarkers):
+		return &ContentFilterError{e}
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden || has(authMarkers):
+		return &AuthError{e}
+	case e.StatusCode == http.StatusTooManyRequests || has(rateLimitMarkers):
+		return &RateLimitError{APIError: e}
+	case has(modelNotFoundMarkers) || strings.Contains(text, "model") &&
+		(e.StatusCode == http.StatusNotFound || strings.Contains(text, "not found") || strings.Contains(text, "does not exist")):
+		return &ModelNotFoundError{APIError: e}
+	case e.StatusCode >= 500 || has(serverMarkers):
+		return &ServerError{e}
+	}
+	return e
+}
+
+// ResponseError reads the body of a non-2xx response and returns the classified error it
+// describes. It understands OpenAI-style bodies ({"error": {"message", "type", "code"}}),
+// which Anthropic's also follow, and {"error": "message"}; anything else becomes the message.
+func ResponseError(providerName string, resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	e := ParseError(providerName, b)
+	e.StatusCode = resp.StatusCode
+	err := Classify(e)
+	var rl *RateLimitError
+	if errors.As(err, &rl) {
+		rl.RetryAfter, _ = retryAfter(resp.Header)
+	}
+	return err
+}
+
+// ParseError parses an error body without classifying it; see ResponseError.
+func ParseError(providerName string, body []byte) *APIError {
+	e := &APIError{Provider: providerName}
+	var wrapper struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &wrapper) == nil && len(wrapper.Error) > 0 {
+		var detail struct {
+			Message string          `json:"message"`
+			Type    string          `json:"type"`
+			Code    json.RawMessage `json:"code"`
+		}
+		var msg string
+		if json.Unmarshal(wrapper.Error, &msg) == nil && msg != "" {
+			e.Message = msg
+			return e
+		}
+		if json.Unmarshal(wrapper.Error, &detail) == nil && detail.Message != "" {
+			e.Message, e.Type = detail.Message, detail.Type
+			// OpenAI sends a string code, LiteLLM sometimes a number or a quoted status
+			if s, err := strconv.Unquote(string(detail.Code)); err == nil {
+				e.Code = s
+			} else if string(detail.Code) != "null" {
+				e.Code = string(detail.Code)
+			}
+			return e
+		}
+	}
+	e.Message = strings.TrimSpace(string(body))
+	return e
+}
+
+// IsContextLengthExceeded reports whether err says the request was too large for the model.
+// Besides a *ContextLengthError it recognizes the messages of untyped errors.
+func IsContextLengthExceeded(err error) bool {
+	if err == nil {
+		return false
+	}
+	var cl *ContextLengthError
+	if errors.As(err, &cl) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range contextLengthMarkers {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsContextLengthExceeded(t *testing.T) {
+	cases := map[string]bool{
+		`{"error":{"code":"context_length_exceeded"}}`:                          true,
+		"litellm.ContextWindowExceededError: prompt is too long: 210000 tokens": true,
+		"This model's maximum context length is 8192 tokens":                    true,
+		"rate limit exceeded": false,
+	}
+	for msg, want := range cases {
+		if got := IsContextLengthExceeded(errors.New(msg)); got != want {
+			t.Errorf("IsContextLengthExceeded(%q) = %v", msg, got)
+		}
+	}
+	if IsContextLengthExceeded(nil) {
+		t.Error("nil error")
+	}
+}
+
+func TestResponseError(t *testing.T) {
+	cases := []struct {
+		status int
+		body   string
+		check  func(error) bool
+	}{
+		{401, `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`,
+			func(err error) bool { var e *AuthError; return errors.As(err, &e) }},
+		{429, `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`,
+			func(err error) bool {
+				var e *RateLimitError
+				return errors.As(err, &e) && e.RetryAfter == 20*time.Second
+			}},
+		{400, `{"error":{"message":"This model's maximum context length is 8192 tokens","type":"invalid_request_error","code":"context_length_exceeded"}}`,
+			func(err error) bool {
+				var e *ContextLengthError
+				return errors.As(err, &e) && IsContextLengthExceeded(err)
+			}},
+		{400, `{"error":{"message":"Invalid model name passed in model=gpt-9. Call /v1/models to view available models","type":"invalid_request_error","param":"model","code":400}}`,
+			func(err error) bool { var e *ModelNotFoundError; return errors.As(err, &e) && e.Code == "400" }},
+		{404, `{"error":"model \"llama9\" not found, try pulling it first"}`,
+			func(err error) bool { var e *ModelNotFoundError; return errors.As(err, &e) }},
+		{400, `{"error":{"message":"The response was filtered due to the prompt triggering content management policy","type":null,"code":"content_filter"}}`,
+			func(err error) bool { var e *ContentFilterError; return errors.As(err, &e) }},
+		{529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			func(err error) bool { var e *ServerError; return errors.As(err, &e) && e.Type == "overloaded_error" }},
+		// Not a model error without mentioning a model, e.g. a wrong base URL
+		{404, `404 page not found`,
+			func(err error) bool {
+				var e *APIError
+				return errors.As(err, &e) && err == error(e) && e.Message == "404 page not found"
+			}},
+	}
+	for _, c := range cases {
+		resp := &http.Response{StatusCode: c.status, Header: http.Header{"Retry-After": {"20"}}, Body: io.NopCloser(strings.NewReader(c.body))}
+		if err := ResponseError("test", resp); !c.check(err) {
+			t.Errorf("%d %s: got %T %v", c.status, c.body, err, err)
+		}
+	}
+}
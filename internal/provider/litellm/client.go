@@ -4,23 +4,23 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
-	"time"
+
+	"github.com/yourname/clichat/internal/provider"
 )
 
 // Client is a LiteLLM API client.
 type Client struct {
 	BaseURL string
 	APIKey  string
-	HTTP    *http.Client
+	HTTP    *provider.Transport
 }
 
 func NewClient(baseURL, apiKey string) *Client {
-	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), APIKey: apiKey, HTTP: &http.Client{Timeout: 60 * time.Second}}
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), APIKey: apiKey, HTTP: provider.NewTransport(provider.DefaultTimeouts, provider.DefaultMaxRetries)}
 }
 
 // ListModels fetches available models.
@@ -38,8 +38,7 @@ func (c *Client) ListModels(ctx context.Context) ([]Model, error) {
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("list models: %s: %s", resp.Status, string(b))
+		return nil, fmt.Errorf("list models: %w", responseError(resp))
 	}
 	var out struct {
 		Data []Model `json:"data"`
@@ -53,13 +52,40 @@ func (c *Client) ListModels(ctx context.Context) ([]Model, error) {
 // StreamChat starts a streaming chat completion.
 // Returns a channel of string deltas and a channel for errors.
 func (c *Client) StreamChat(ctx context.Context, reqPayload ChatRequest) (<-chan string, <-chan error) {
+	deltas, _, errs := c.StreamChatUsage(ctx, reqPayload)
+	return deltas, errs
+}
+
+// EncodeRequest returns the /v1/chat/completions body StreamChatUsage sends for req, which
+// asks for a final usage chunk when streaming.
+func (c *Client) EncodeRequest(_ context.Context, req ChatRequest) ([]byte, error) {
+	payload := chatRequest{ChatRequest: req}
+	if req.Stream {
+		payload.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+	return json.Marshal(payload)
+}
+
+// StreamChatUsage is StreamChat with provider-reported token usage: it asks for a final usage
+// chunk and delivers it on the usage channel, which is buffered and closed before deltas, so it
+// can be read without blocking once deltas is closed. No value is sent if the provider reports
+// no usage.
+func (c *Client) StreamChatUsage(ctx context.Context, reqPayload ChatRequest) (<-chan string, <-chan Usage, <-chan error) {
 	deltas := make(chan string)
+	usage := make(chan Usage, 1)
 	errs := make(chan error, 1)
 	go func() {
 		defer close(deltas)
 		defer close(errs)
+		var last *Usage
+		defer func() {
+			if last != nil {
+				usage <- *last
+			}
+			close(usage)
+		}()
 
-		bodyBytes, err := json.Marshal(reqPayload)
+		bodyBytes, err := c.EncodeRequest(ctx, reqPayload)
 		if err != nil {
 			errs <- err
 			return
@@ -82,8 +108,7 @@ func (c *Client) StreamChat(ctx context.Context, reqPayload ChatRequest) (<-chan
 		}
 		defer resp.Body.Close()
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			b, _ := io.ReadAll(resp.Body)
-			errs <- errors.New(string(b))
+			errs <- responseError(resp)
 			return
 		}
 
@@ -104,8 +129,18 @@ func (c *Client) StreamChat(ctx context.Context, reqPayload ChatRequest) (<-chan
 								Content string `json:"content"`
 							} `json:"delta"`
 						} `json:"choices"`
+						Usage *Usage          `json:"usage"`
+						Error json.RawMessage `json:"error"`
 					}
 					if err := json.Unmarshal([]byte(data), &chunk); err == nil {
+						// LiteLLM reports failures after the response started as an error chunk
+						if len(chunk.Error) > 0 && string(chunk.Error) != "null" {
+							errs <- provider.Classify(provider.ParseError("litellm", []byte(data)))
+							return
+						}
+						if chunk.Usage != nil {
+							last = chunk.Usage
+						}
 						if len(chunk.Choices) > 0 {
 							deltas <- chunk.Choices[0].Delta.Content
 						}
@@ -121,5 +156,10 @@ func (c *Client) StreamChat(ctx context.Context, reqPayload ChatRequest) (<-chan
 			}
 		}
 	}()
-	return deltas, errs
+	return deltas, usage, errs
+}
+
+// responseError turns an error response into a typed provider error.
+func responseError(resp *http.Response) error {
+	return provider.ResponseError("litellm", resp)
 }
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/yourname/clichat/internal/provider"
 )
 
 func TestListModels(t *testing.T) {
@@ -58,3 +61,107 @@ func TestStreamChat(t *testing.T) {
 		t.Fatalf("got %q", got)
 	}
 }
+
+func TestStreamChatUsage(t *testing.T) {
+	var got chatRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}],\"usage\":null}\n\n"))
+		_, _ = w.Write([]byte("data: {\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":1,\"total_tokens\":13}}\n\n"))
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	c := NewClient(ts.URL, "")
+	deltas, usage, errs := c.StreamChatUsage(context.Background(), ChatRequest{Model: "m1", Messages: []ChatMessage{{Role: "user", Content: "hi"}}, Stream: true})
+	var sb strings.Builder
+	for d := range deltas {
+		sb.WriteString(d)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if sb.String() != "Hi" {
+		t.Fatalf("got %q", sb.String())
+	}
+	if got.StreamOptions == nil || !got.StreamOptions.IncludeUsage {
+		t.Fatalf("stream_options.include_usage not sent: %+v", got)
+	}
+	u, ok := <-usage
+	if !ok || u != (Usage{PromptTokens: 12, CompletionTokens: 1, TotalTokens: 13}) {
+		t.Fatalf("usage = %+v, %v", u, ok)
+	}
+}
+
+func TestProviderStreamChatUsage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n"))
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\n"))
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer ts.Close()
+	var p provider.Provider = NewProvider(NewClient(ts.URL, ""), nil)
+	deltas, usage, errs := p.StreamChatUsage(context.Background(), ChatRequest{Model: "m1", Messages: []ChatMessage{{Role: "user", Content: "hi"}}, Stream: true})
+	var sb strings.Builder
+	for d := range deltas {
+		sb.WriteString(d)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if sb.String() != "Hello" {
+		t.Fatalf("got %q", sb.String())
+	}
+	if u, ok := <-usage; ok {
+		t.Fatalf("no usage was reported, got %+v", u)
+	}
+}
+
+func TestModelInfoCache(t *testing.T) {
+	calls := 0
+	mux := http.NewServeMux()
+	// Only the versioned path exists, as on some proxies
+	mux.HandleFunc("/v1/model/info", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"data":[
+			{"model_name":"big","model_info":{"max_input_tokens":1000000,"max_output_tokens":8192,"supported_openai_params":["max_tokens","stream"],"input_cost_per_token":1e-06,"output_cost_per_token":4e-06}},
+			{"model_name":"big","model_info":{"max_input_tokens":1}},
+			{"model_name":"old","model_info":{"max_input_tokens":null,"max_tokens":8192}}
+		]}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	path := filepath.Join(t.TempDir(), "model_info.json")
+	cache := NewModelInfoCache(NewClient(ts.URL, ""), path, 0)
+
+	big, ok := cache.Lookup(context.Background(), "big")
+	if !ok || big.MaxInputTokens != 1000000 || big.MaxOutputTokens != 8192 || big.InputCostPerToken != 1e-6 || big.OutputCostPerToken != 4e-6 {
+		t.Fatalf("big = %+v, %v", big, ok)
+	}
+	if big.Supports("temperature") || !big.Supports("stream") {
+		t.Fatalf("supported params not applied: %+v", big)
+	}
+	if old, _ := cache.Lookup(context.Background(), "old"); old.MaxInputTokens != 8192 || !old.Supports("temperature") {
+		t.Fatalf("old = %+v", old)
+	}
+	// Data fetched by this process is not fetched again for unknown models
+	cache.Lookup(context.Background(), "nope")
+	if calls != 1 {
+		t.Fatalf("calls = %d, want 1", calls)
+	}
+
+	// A fresh cache file is used without querying the proxy, except once for an unknown model
+	again := NewModelInfoCache(NewClient(ts.URL, ""), path, 0)
+	if info, ok := again.Lookup(context.Background(), "big"); !ok || info.MaxInputTokens != 1000000 {
+		t.Fatalf("cached big = %+v, %v", info, ok)
+	}
+	if calls != 1 {
+		t.Fatalf("cache file not used: %d calls", calls)
+	}
+	again.Lookup(context.Background(), "nope")
+	again.Lookup(context.Background(), "nope")
+	if calls != 2 {
+		t.Fatalf("calls = %d, want 2", calls)
+	}
+}
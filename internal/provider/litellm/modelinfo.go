@@ -0,0 +1,197 @@
+package litellm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ListModelInfo fetches model details from /model/info, falling back to /v1/model/info on
+// proxies that only serve the versioned path. Deployments sharing a model name are reported once.
+func (c *Client) ListModelInfo(ctx context.Context) ([]ModelInfo, error) {
+	var out struct {
+		Data []struct {
+			ModelName string `json:"model_name"`
+			Info      struct {
+				MaxInputTokens  *int     `json:"max_input_tokens"`
+				MaxOutputTokens *int     `json:"max_output_tokens"`
+				MaxTokens       *int     `json:"max_tokens"`
+				SupportedParams []string `json:"supported_openai_params"`
+				InputCost       *float64 `json:"input_cost_per_token"`
+				OutputCost      *float64 `json:"output_cost_per_token"`
+			} `json:"model_info"`
+		} `json:"data"`
+	}
+	var err error
+	for _, path := range []string{"/model/info", "/v1/model/info"} {
+		if err = c.getJSON(ctx, path, &out); err != errNotFound {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("model info: %w", err)
+	}
+	seen := map[string]bool{}
+	var infos []ModelInfo
+	for _, d := range out.Data {
+		if d.ModelName == "" || seen[d.ModelName] {
+			continue
+		}
+		seen[d.ModelName] = true
+		info := ModelInfo{Name: d.ModelName, SupportedParams: d.Info.SupportedParams}
+		if v := d.Info.MaxInputTokens; v != nil {
+			info.MaxInputTokens = *v
+		} else if v := d.Info.MaxTokens; v != nil {
+			info.MaxInputTokens = *v
+		}
+		if v := d.Info.MaxOutputTokens; v != nil {
+			info.MaxOutputTokens = *v
+		}
+		if d.Info.InputCost != nil && d.Info.OutputCost != nil {
+			info.InputCostPerToken, info.OutputCostPerToken = *d.Info.InputCost, *d.Info.OutputCost
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+var errNotFound = errors.New("not found")
+
+func (c *Client) getJSON(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// DefaultModelInfoTTL is how long cached model info is used before it is fetched again.
+const DefaultModelInfoTTL = 24 * time.Hour
+
+// ModelInfoCache looks up model info, keeping the last /model/info result in a JSON file so
+// that every start does not have to query the proxy. A stale or unreadable cache is refreshed
+// on first use; if that fails, stale data is still used.
+type ModelInfoCache struct {
+	client *Client
+	path   string
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	loaded    bool
+	refreshed bool
+	models    map[string]ModelInfo
+}
+
+type modelInfoFile struct {
+	// Version changes whenever ModelInfo gains fields, so older cache files are refetched.
+	Version   int         `json:"version"`
+	FetchedAt time.Time   `json:"fetched_at"`
+	Models    []ModelInfo `json:"models"`
+}
+
+const modelInfoVersion = 2
+
+// NewModelInfoCache returns a cache backed by path; an empty path keeps results in memory only.
+// A zero ttl means DefaultModelInfoTTL.
+func NewModelInfoCache(client *Client, path string, ttl time.Duration) *ModelInfoCache {
+	if ttl <= 0 {
+		ttl = DefaultModelInfoTTL
+	}
+	return &ModelInfoCache{client: client, path: path, ttl: ttl}
+}
+
+// Lookup returns the info for model. A model missing from cached data triggers one refresh
+// per process, so newly added models are picked up without waiting for the TTL.
+func (m *ModelInfoCache) Lookup(ctx context.Context, model string) (ModelInfo, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.loaded {
+		m.loaded = true
+		f, err := m.read()
+		if err == nil {
+			m.models = index(f.Models)
+		}
+		if err != nil || time.Since(f.FetchedAt) >= m.ttl {
+			_ = m.refresh(ctx)
+		}
+	}
+	info, ok := m.models[model]
+	if !ok && !m.refreshed {
+		if m.refresh(ctx) == nil {
+			info, ok = m.models[model]
+		}
+	}
+	return info, ok
+}
+
+// Refresh fetches model info now and rewrites the cache file.
+func (m *ModelInfoCache) Refresh(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loaded = true
+	return m.refresh(ctx)
+}
+
+func (m *ModelInfoCache) refresh(ctx context.Context) error {
+	m.refreshed = true
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	infos, err := m.client.ListModelInfo(ctx)
+	if err != nil {
+		return err
+	}
+	m.models = index(infos)
+	if m.path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(modelInfoFile{Version: modelInfoVersion, FetchedAt: time.Now().UTC(), Models: infos}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.path, b, 0600)
+}
+
+func (m *ModelInfoCache) read() (*modelInfoFile, error) {
+	if m.path == "" {
+		return nil, os.ErrNotExist
+	}
+	b, err := os.ReadFile(m.path)
+	if err != nil {
+		return nil, err
+	}
+	var f modelInfoFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+	if f.Version != modelInfoVersion {
+		return nil, fmt.Errorf("%s: outdated cache version %d", m.path, f.Version)
+	}
+	return &f, nil
+}
+
+func index(infos []ModelInfo) map[string]ModelInfo {
+	out := make(map[string]ModelInfo, len(infos))
+	for _, i := range infos {
+		out[i.Name] = i
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package litellm
+
+import (
+	"context"
+
+	"github.com/yourname/clichat/internal/config"
+	"github.com/yourname/clichat/internal/provider"
+)
+
+func init() {
+	provider.Register("litellm", func(cfg *config.Config) (provider.Provider, error) {
+		c := NewClient(cfg.LiteLLMBaseURL, cfg.LiteLLMAPIKey)
+		c.HTTP = provider.TransportFor(cfg)
+		var info *ModelInfoCache
+		if cfg.ModelInfo {
+			info = NewModelInfoCache(c, cfg.ModelInfoCache, 0)
+		}
+		return NewProvider(c, info), nil
+	})
+}
+
+// Provider is the LiteLLM backend: a Client plus model info from /model/info. It also
+// implements provider.Embedder.
+type Provider struct {
+	*Client
+	info *ModelInfoCache
+}
+
+// NewProvider returns a provider using c; a nil info cache disables model info lookups.
+func NewProvider(c *Client, info *ModelInfoCache) *Provider {
+	return &Provider{Client: c, info: info}
+}
+
+// ModelInfo returns what LiteLLM reports about model.
+func (p *Provider) ModelInfo(ctx context.Context, model string) (ModelInfo, bool) {
+	if p.info == nil {
+		return ModelInfo{}, false
+	}
+	return p.info.Lookup(ctx, model)
+}
+
+var (
+	_ provider.Provider       = (*Provider)(nil)
+	_ provider.Embedder       = (*Provider)(nil)
+	_ provider.RequestEncoder = (*Provider)(nil)
+)
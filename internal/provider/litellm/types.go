@@ -1,30 +1,23 @@
 package litellm
 
-type Model struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-}
-
-type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
+import "github.com/yourname/clichat/internal/provider"
 
-type Tool struct {
-	Type string `json:"type"`
-}
+// The request and response types are shared by all providers.
+type (
+	Model       = provider.Model
+	ChatMessage = provider.ChatMessage
+	Tool        = provider.Tool
+	ChatRequest = provider.ChatRequest
+	Usage       = provider.Usage
+	ModelInfo   = provider.ModelInfo
+)
 
-type ChatRequest struct {
-	Model       string        `json:"model"`
-	Messages    []ChatMessage `json:"messages"`
-	Temperature float64       `json:"temperature,omitempty"`
-	TopP        float64       `json:"top_p,omitempty"`
-	Stream      bool          `json:"stream"`
-	Tools       []Tool        `json:"tools,omitempty"`
+// chatRequest is a ChatRequest as sent to /v1/chat/completions.
+type chatRequest struct {
+	ChatRequest
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
 }
 
-type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
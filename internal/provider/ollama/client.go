@@ -0,0 +1,247 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourname/clichat/internal/provider"
+)
+
+// DefaultBaseURL is where a local Ollama server listens.
+const DefaultBaseURL = "http://localhost:11434"
+
+// Client is a client for Ollama's native API.
+type Client struct {
+	BaseURL string
+	// NumCtx is the context window requested for every model (options.num_ctx), capped by the
+	// model's own context length. Zero leaves it to the server, whose default is small.
+	NumCtx int
+	HTTP   *provider.Transport
+
+	mu     sync.Mutex
+	ctxLen map[string]int
+}
+
+func NewClient(baseURL string, numCtx int) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), NumCtx: numCtx, HTTP: provider.NewTransport(provider.DefaultTimeouts, provider.DefaultMaxRetries), ctxLen: map[string]int{}}
+}
+
+type options struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	NumCtx      int      `json:"num_ctx,omitempty"`
+}
+
+type chatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []provider.ChatMessage `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Options  *options               `json:"options,omitempty"`
+}
+
+// chatChunk is one line of a /api/chat stream; the last one has Done set and the token counts.
+type chatChunk struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+// EncodeRequest returns the /api/chat body StreamChatUsage sends for req, with sampling
+// parameters and num_ctx as options.
+func (c *Client) EncodeRequest(ctx context.Context, req provider.ChatRequest) ([]byte, error) {
+	payload := chatRequest{Model: req.Model, Messages: req.Messages, Stream: req.Stream}
+	opts := options{NumCtx: c.numCtx(ctx, req.Model), Temperature: req.Temperature, TopP: req.TopP}
+	if opts != (options{}) {
+		payload.Options = &opts
+	}
+	return json.Marshal(payload)
+}
+
+// StreamChatUsage streams an answer from /api/chat, which sends one JSON object per line.
+// Usage comes from prompt_eval_count and eval_count; none is reported when Ollama reused its
+// prompt cache and left prompt_eval_count out. Tools are not sent.
+func (c *Client) StreamChatUsage(ctx context.Context, reqPayload provider.ChatRequest) (<-chan string, <-chan provider.Usage, <-chan error) {
+	deltas := make(chan string)
+	usage := make(chan provider.Usage, 1)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(deltas)
+		defer close(errs)
+		var last *provider.Usage
+		defer func() {
+			if last != nil {
+				usage <- *last
+			}
+			close(usage)
+		}()
+
+		bodyBytes, err := c.EncodeRequest(ctx, reqPayload)
+		if err != nil {
+			errs <- err
+			return
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/api/chat", bytes.NewReader(bodyBytes))
+		if err != nil {
+			errs <- err
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			errs <- responseError(resp)
+			return
+		}
+
+		reader := bufio.NewReader(resp.Body)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				var chunk chatChunk
+				if err := json.Unmarshal(line, &chunk); err != nil {
+					errs <- fmt.Errorf("ollama: bad stream line: %w", err)
+					return
+				}
+				if chunk.Error != "" {
+					errs <- provider.Classify(&provider.APIError{Provider: "ollama", Message: chunk.Error})
+					return
+				}
+				if chunk.Message.Content != "" {
+					deltas <- chunk.Message.Content
+				}
+				if chunk.Done {
+					if chunk.PromptEvalCount > 0 {
+						last = &provider.Usage{PromptTokens: chunk.PromptEvalCount, CompletionTokens: chunk.EvalCount,
+							TotalTokens: chunk.PromptEvalCount + chunk.EvalCount}
+					}
+					return
+				}
+			}
+			if err != nil {
+				if err == io.EOF {
+					return
+				}
+				errs <- err
+				return
+			}
+		}
+	}()
+	return deltas, usage, errs
+}
+
+// ListModels lists the locally installed models from /api/tags, with their size and family.
+func (c *Client) ListModels(ctx context.Context) ([]provider.Model, error) {
+	var out struct {
+		Models []struct {
+			Name    string `json:"name"`
+			Size    int64  `json:"size"`
+			Details struct {
+				Family            string `json:"family"`
+				ParameterSize     string `json:"parameter_size"`
+				QuantizationLevel string `json:"quantization_level"`
+			} `json:"details"`
+		} `json:"models"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/tags", nil, &out); err != nil {
+		return nil, fmt.Errorf("list models: %w", err)
+	}
+	models := make([]provider.Model, 0, len(out.Models))
+	for _, m := range out.Models {
+		models = append(models, provider.Model{
+			ID: m.Name, Size: m.Size, Family: m.Details.Family,
+			ParameterSize: m.Details.ParameterSize, Quantization: m.Details.QuantizationLevel,
+		})
+	}
+	return models, nil
+}
+
+// ModelInfo reports the context window requests to model get: NumCtx, capped by the
+// model's context length from /api/show.
+func (c *Client) ModelInfo(ctx context.Context, model string) (provider.ModelInfo, bool) {
+	n := c.numCtx(ctx, model)
+	if n == 0 {
+		return provider.ModelInfo{}, false
+	}
+	return provider.ModelInfo{Name: model, MaxInputTokens: n}, true
+}
+
+// numCtx returns the num_ctx to request for model, or 0 to use the server default.
+func (c *Client) numCtx(ctx context.Context, model string) int {
+	if c.NumCtx <= 0 {
+		return 0
+	}
+	if n := c.contextLength(ctx, model); n > 0 && n < c.NumCtx {
+		return n
+	}
+	return c.NumCtx
+}
+
+// contextLength returns the context length model was trained with, or 0 if unknown. Results,
+// including failures, are kept for the life of the client.
+func (c *Client) contextLength(ctx context.Context, model string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n, ok := c.ctxLen[model]; ok {
+		return n
+	}
+	var out struct {
+		ModelInfo map[string]any `json:"model_info"`
+	}
+	body, _ := json.Marshal(map[string]string{"model": model})
+	n := 0
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if c.do(ctx, http.MethodPost, "/api/show", body, &out) == nil {
+		// The key is prefixed with the architecture, e.g. "llama.context_length"
+		for k, v := range out.ModelInfo {
+			if f, ok := v.(float64); ok && strings.HasSuffix(k, ".context_length") {
+				n = int(f)
+			}
+		}
+	}
+	c.ctxLen[model] = n
+	return n
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte, v any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// responseError turns an error response, normally {"error": "..."}, into a typed provider error.
+func responseError(resp *http.Response) error {
+	return provider.ResponseError("ollama", resp)
+}
@@ -0,0 +1,119 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yourname/clichat/internal/provider"
+)
+
+// fakeOllama serves /api/show with a 4096-token model and the given /api/chat handler.
+func fakeOllama(t *testing.T, chat http.HandlerFunc) (*httptest.Server, *int) {
+	t.Helper()
+	shows := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/show", func(w http.ResponseWriter, r *http.Request) {
+		shows++
+		fmt.Fprint(w, `{"details":{"family":"llama"},"model_info":{"general.architecture":"llama","llama.context_length":4096}}`)
+	})
+	mux.HandleFunc("/api/chat", chat)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts, &shows
+}
+
+func TestStreamChat(t *testing.T) {
+	var got chatRequest
+	ts, shows := fakeOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":"Hel"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":"lo"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":26,"eval_count":2}`)
+	})
+	c := NewClient(ts.URL, 8192)
+	temp, topP := 0.2, 0.9
+	deltas, usage, errs := c.StreamChatUsage(context.Background(), provider.ChatRequest{
+		Model: "llama3", Messages: []provider.ChatMessage{{Role: "system", Content: "sys"}, {Role: "user", Content: "hi"}},
+		Temperature: &temp, TopP: &topP, Stream: true,
+	})
+	var sb strings.Builder
+	for d := range deltas {
+		sb.WriteString(d)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if sb.String() != "Hello" {
+		t.Fatalf("got %q", sb.String())
+	}
+	// num_ctx is capped by the model's context length
+	if o := got.Options; o == nil || o.NumCtx != 4096 || o.Temperature == nil || *o.Temperature != 0.2 || o.TopP == nil || *o.TopP != 0.9 {
+		t.Fatalf("unexpected options: %+v", got.Options)
+	}
+	if len(got.Messages) != 2 || got.Messages[0].Role != "system" || !got.Stream {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+	u, ok := <-usage
+	if !ok || u != (provider.Usage{PromptTokens: 26, CompletionTokens: 2, TotalTokens: 28}) {
+		t.Fatalf("usage = %+v, %v", u, ok)
+	}
+	if info, ok := c.ModelInfo(context.Background(), "llama3"); !ok || info.MaxInputTokens != 4096 || *shows != 1 {
+		t.Fatalf("info = %+v, %v after %d /api/show calls", info, ok, *shows)
+	}
+}
+
+func TestStreamChatErrors(t *testing.T) {
+	ts, _ := fakeOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"model \"missing\" not found, try pulling it first"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"message":{"content":"Hi"},"done":false}`)
+		fmt.Fprintln(w, `{"error":"llama runner process has terminated"}`)
+	})
+	c := NewClient(ts.URL, 0)
+	stream := func(model string) (string, error) {
+		deltas, _, errs := c.StreamChatUsage(context.Background(), provider.ChatRequest{Model: model, Messages: []provider.ChatMessage{{Role: "user", Content: "hi"}}, Stream: true})
+		var sb strings.Builder
+		for d := range deltas {
+			sb.WriteString(d)
+		}
+		return sb.String(), <-errs
+	}
+	if _, err := stream("missing"); err == nil || !strings.Contains(err.Error(), "try pulling it first") {
+		t.Fatalf("want the server's error, got %v", err)
+	}
+	if text, err := stream("crashy"); text != "Hi" || err == nil || !strings.Contains(err.Error(), "terminated") {
+		t.Fatalf("want partial text and the stream error, got %q, %v", text, err)
+	}
+	if _, ok := c.ModelInfo(context.Background(), "crashy"); ok {
+		t.Fatal("no model info expected without OLLAMA_NUM_CTX")
+	}
+}
+
+func TestListModels(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"models":[{"name":"llama3:latest","model":"llama3:latest","size":4661224676,
+			"details":{"format":"gguf","family":"llama","families":["llama"],"parameter_size":"8.0B","quantization_level":"Q4_0"}}]}`)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	mods, err := NewClient(ts.URL, 0).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	want := provider.Model{ID: "llama3:latest", Size: 4661224676, Family: "llama", ParameterSize: "8.0B", Quantization: "Q4_0"}
+	if len(mods) != 1 || mods[0] != want {
+		t.Fatalf("unexpected models: %+v", mods)
+	}
+}
@@ -0,0 +1,19 @@
+package ollama
+
+import (
+	"github.com/yourname/clichat/internal/config"
+	"github.com/yourname/clichat/internal/provider"
+)
+
+func init() {
+	provider.Register("ollama", func(cfg *config.Config) (provider.Provider, error) {
+		c := NewClient(cfg.OllamaBaseURL, cfg.OllamaNumCtx)
+		c.HTTP = provider.TransportFor(cfg)
+		return c, nil
+	})
+}
+
+var (
+	_ provider.Provider       = (*Client)(nil)
+	_ provider.RequestEncoder = (*Client)(nil)
+)
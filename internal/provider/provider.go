@@ -0,0 +1,91 @@
+// Package provider defines the interface chat backends implement and the types they share.
+package provider
+
+import "context"
+
+// Provider is an LLM backend: it streams chat completions, lists models and describes what
+// a model supports.
+type Provider interface {
+	// StreamChatUsage streams the answer to req as text deltas. The usage channel receives the
+	// token usage the backend reports, if any; it is buffered and closed before deltas, so it
+	// can be read without blocking once deltas is closed. The error channel receives at most
+	// one error.
+	StreamChatUsage(ctx context.Context, req ChatRequest) (<-chan string, <-chan Usage, <-chan error)
+	// ListModels returns the models the backend serves.
+	ListModels(ctx context.Context) ([]Model, error)
+	// ModelInfo describes model; ok is false when the backend does not know it.
+	ModelInfo(ctx context.Context, model string) (info ModelInfo, ok bool)
+}
+
+// RequestEncoder is implemented by providers that can show the body they would send for a
+// chat request without sending it.
+type RequestEncoder interface {
+	EncodeRequest(ctx context.Context, req ChatRequest) ([]byte, error)
+}
+
+// Embedder is implemented by providers that compute embeddings.
+type Embedder interface {
+	// Embed returns one embedding vector per input, in input order.
+	Embed(ctx context.Context, model string, inputs []string) ([][]float32, error)
+}
+
+type Model struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Optional details reported by local backends; Size is in bytes.
+	Size          int64  `json:"size,omitempty"`
+	Family        string `json:"family,omitempty"`
+	ParameterSize string `json:"parameter_size,omitempty"`
+	Quantization  string `json:"quantization,omitempty"`
+}
+
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type Tool struct {
+	Type string `json:"type"`
+}
+
+// ChatRequest is a provider-neutral chat completion request. Temperature and TopP are nil
+// when not sent, so an explicit 0 reaches the provider.
+type ChatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []ChatMessage `json:"messages"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	TopP        *float64      `json:"top_p,omitempty"`
+	Stream      bool          `json:"stream"`
+	Tools       []Tool        `json:"tools,omitempty"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ModelInfo describes a model's limits, parameters and pricing.
+type ModelInfo struct {
+	Name            string   `json:"name"`
+	MaxInputTokens  int      `json:"max_input_tokens,omitempty"`
+	MaxOutputTokens int      `json:"max_output_tokens,omitempty"`
+	SupportedParams []string `json:"supported_params,omitempty"`
+	// Prices in USD per token; zero when the backend has no pricing for the model.
+	InputCostPerToken  float64 `json:"input_cost_per_token,omitempty"`
+	OutputCostPerToken float64 `json:"output_cost_per_token,omitempty"`
+}
+
+// Supports reports whether the model accepts an OpenAI request parameter such as "temperature".
+// Models without a known parameter list are assumed to accept everything.
+func (m ModelInfo) Supports(param string) bool {
+	if len(m.SupportedParams) == 0 {
+		return true
+	}
+	for _, p := range m.SupportedParams {
+		if p == param {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/yourname/clichat/internal/config"
+)
+
+// Factory creates a provider from the configuration.
+type Factory func(cfg *config.Config) (Provider, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{}
+)
+
+// Register makes a provider available under name, the value LLM_PROVIDER selects it with.
+// It is meant to be called from the provider package's init and panics on duplicate names.
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := factories[name]; dup {
+		panic("provider: Register called twice for " + name)
+	}
+	factories[name] = f
+}
+
+// Names returns the registered provider names in alphabetical order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the provider cfg.Provider names.
+func New(cfg *config.Config) (Provider, error) {
+	name := strings.ToLower(strings.TrimSpace(cfg.Provider))
+	mu.RLock()
+	f, ok := factories[name]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("LLM_PROVIDER: unknown provider %q (available: %s)", cfg.Provider, strings.Join(Names(), ", "))
+	}
+	return f(cfg)
+}
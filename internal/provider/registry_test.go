@@ -0,0 +1,38 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/yourname/clichat/internal/config"
+)
+
+type fake struct{}
+
+func (fake) StreamChatUsage(context.Context, ChatRequest) (<-chan string, <-chan Usage, <-chan error) {
+	return nil, nil, nil
+}
+func (fake) ListModels(context.Context) ([]Model, error)         { return []Model{{ID: "f"}}, nil }
+func (fake) ModelInfo(context.Context, string) (ModelInfo, bool) { return ModelInfo{}, false }
+
+func TestRegistry(t *testing.T) {
+	Register("fake", func(*config.Config) (Provider, error) { return fake{}, nil })
+
+	p, err := New(&config.Config{Provider: " Fake "})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if mods, _ := p.ListModels(context.Background()); len(mods) != 1 || mods[0].ID != "f" {
+		t.Fatalf("wrong provider: %+v", mods)
+	}
+	if _, err := New(&config.Config{Provider: "nope"}); err == nil || !strings.Contains(err.Error(), "fake") {
+		t.Fatalf("want an error listing the registered providers, got %v", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice should panic")
+		}
+	}()
+	Register("fake", nil)
+}
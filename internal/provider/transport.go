@@ -0,0 +1,245 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yourname/clichat/internal/config"
+)
+
+// Timeouts bound the phases of a provider request instead of its total duration, so long
+// answers are never cut off while data keeps arriving. Zero disables a timeout.
+type Timeouts struct {
+	// Connect bounds dialing and the TLS handshake.
+	Connect time.Duration
+	// FirstToken bounds the wait from sending a request to the first data of its response.
+	FirstToken time.Duration
+	// Idle bounds the gap between two chunks of a response.
+	Idle time.Duration
+}
+
+// DefaultTimeouts are used by clients created without a configuration.
+var DefaultTimeouts = Timeouts{Connect: 10 * time.Second, FirstToken: 2 * time.Minute, Idle: time.Minute}
+
+// DefaultMaxRetries is how often a failed request is retried by default.
+const DefaultMaxRetries = 3
+
+const (
+	// retryBaseDelay is the first backoff delay; it doubles with every retry.
+	retryBaseDelay = time.Second
+	// maxRetryDelay caps backoff delays. A longer Retry-After is not waited for: the response
+	// is returned as is.
+	maxRetryDelay = time.Minute
+)
+
+// StallError reports a response that stopped delivering data.
+type StallError struct {
+	// FirstToken is set when no data arrived at all.
+	FirstToken bool
+	After      time.Duration
+}
+
+func (e *StallError) Error() string {
+	if e.FirstToken {
+		return fmt.Sprintf("no response from the provider within %s", e.After)
+	}
+	return fmt.Sprintf("response stalled: no data for %s", e.After)
+}
+
+// Transport sends provider requests with Timeouts, and retries requests that fail before any
+// response data arrived: connection errors, and 429 and 5xx responses, which are retried with
+// exponential backoff or after the delay their Retry-After header asks for.
+type Transport struct {
+	HTTP       *http.Client
+	Timeouts   Timeouts
+	MaxRetries int
+
+	// sleep waits between retries; tests replace it.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewTransport returns a transport whose HTTP client has no overall timeout.
+func NewTransport(t Timeouts, maxRetries int) *Transport {
+	dialer := &net.Dialer{Timeout: t.Connect, KeepAlive: 30 * time.Second}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.DialContext = dialer.DialContext
+	tr.TLSHandshakeTimeout = t.Connect
+	return &Transport{HTTP: &http.Client{Transport: tr}, Timeouts: t, MaxRetries: maxRetries, sleep: sleepCtx}
+}
+
+// TransportFor returns a transport with the timeouts and retries of cfg.
+func TransportFor(cfg *config.Config) *Transport {
+	return NewTransport(Timeouts{Connect: cfg.ConnectTimeout, FirstToken: cfg.FirstTokenTimeout, Idle: cfg.IdleTimeout}, cfg.MaxRetries)
+}
+
+// Do sends req like http.Client.Do. Reads from the response body fail with a *StallError
+// when the response stalls. Retries resend the request body, so requests whose body cannot
+// be replayed (see http.Request.GetBody) are not retried.
+func (t *Transport) Do(req *http.Request) (*http.Response, error) {
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithCancelCause(req.Context())
+		r := req.Clone(ctx)
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel(nil)
+				return nil, err
+			}
+			r.Body = body
+		}
+		w := newWatchdog(cancel, t.Timeouts)
+		resp, err := t.HTTP.Do(r)
+		retry := attempt < t.MaxRetries && replayable
+		if err != nil {
+			w.stop()
+			if cause := context.Cause(ctx); cause != nil && req.Context().Err() == nil {
+				err = cause
+			}
+			cancel(nil)
+			var stall *StallError
+			if !retry || req.Context().Err() != nil || errors.As(err, &stall) {
+				return nil, err
+			}
+			if err := t.sleep(req.Context(), backoff(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if retry && retryableStatus(resp.StatusCode) {
+			delay, ok := retryAfter(resp.Header)
+			if !ok {
+				delay = backoff(attempt)
+			}
+			if delay <= maxRetryDelay {
+				_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+				resp.Body.Close()
+				w.stop()
+				cancel(nil)
+				if err := t.sleep(req.Context(), delay); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		resp.Body = &watchedBody{ReadCloser: resp.Body, ctx: ctx, cancel: cancel, w: w}
+		return resp, nil
+	}
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(h http.Header) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// backoff returns the delay before retry attempt+1: exponential with up to 50% jitter.
+func backoff(attempt int) time.Duration {
+	d := retryBaseDelay << attempt
+	if d <= 0 || d > maxRetryDelay/2 {
+		d = maxRetryDelay / 2
+	}
+	return d + rand.N(d/2+1)
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// watchdog cancels a request whose response does not start within the first-token timeout
+// or pauses longer than the idle timeout.
+type watchdog struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	cancel  context.CancelCauseFunc
+	idle    time.Duration
+	stopped bool
+}
+
+func newWatchdog(cancel context.CancelCauseFunc, t Timeouts) *watchdog {
+	w := &watchdog{cancel: cancel, idle: t.Idle}
+	w.arm(t.FirstToken, &StallError{FirstToken: true, After: t.FirstToken})
+	return w
+}
+
+// arm replaces the running timeout with one that cancels the request with err after d.
+func (w *watchdog) arm(d time.Duration, err error) {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if d > 0 {
+		w.timer = time.AfterFunc(d, func() { w.cancel(err) })
+	}
+}
+
+// data records that response data arrived and restarts the idle timeout.
+func (w *watchdog) data() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.stopped {
+		w.arm(w.idle, &StallError{After: w.idle})
+	}
+}
+
+func (w *watchdog) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	w.arm(0, nil)
+}
+
+type watchedBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	w      *watchdog
+}
+
+func (b *watchedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.w.data()
+	}
+	if err != nil && err != io.EOF {
+		var stall *StallError
+		if errors.As(context.Cause(b.ctx), &stall) {
+			err = stall
+		}
+	}
+	return n, err
+}
+
+func (b *watchedBody) Close() error {
+	b.w.stop()
+	b.cancel(nil)
+	return b.ReadCloser.Close()
+}
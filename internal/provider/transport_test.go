@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTransport returns a transport that records retry delays instead of sleeping.
+func testTransport(t Timeouts, maxRetries int) (*Transport, *[]time.Duration) {
+	tr := NewTransport(t, maxRetries)
+	var delays []time.Duration
+	tr.sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	return tr, &delays
+}
+
+func get(t *testing.T, tr *Transport, url string) (string, int, error) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := tr.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	return string(b), resp.StatusCode, err
+}
+
+func TestTransportRetries(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if b, _ := io.ReadAll(r.Body); string(b) != "payload" {
+			t.Errorf("attempt %d sent body %q", calls, b)
+		}
+		switch calls {
+		case 1:
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			fmt.Fprint(w, "ok")
+		}
+	}))
+	defer ts.Close()
+
+	tr, delays := testTransport(DefaultTimeouts, 3)
+	body, code, err := get(t, tr, ts.URL)
+	if err != nil || code != http.StatusOK || body != "ok" {
+		t.Fatalf("got %q, %d, %v", body, code, err)
+	}
+	if calls != 3 || len(*delays) != 2 || (*delays)[0] != 7*time.Second || (*delays)[1] < 2*time.Second {
+		t.Fatalf("calls = %d, delays = %v", calls, *delays)
+	}
+
+	// Client errors are not retried, and retries stop at MaxRetries
+	for _, tc := range []struct {
+		status, retries, calls int
+	}{{http.StatusBadRequest, 3, 1}, {http.StatusBadGateway, 2, 3}} {
+		calls = 0
+		ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(tc.status)
+		})
+		tr, _ := testTransport(DefaultTimeouts, tc.retries)
+		if _, code, err := get(t, tr, ts.URL); err != nil || code != tc.status || calls != tc.calls {
+			t.Fatalf("status %d: got %d after %d calls, %v", tc.status, code, calls, err)
+		}
+	}
+}
+
+func TestTransportStalls(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		switch r.URL.Path {
+		case "/slow-start":
+			time.Sleep(200 * time.Millisecond)
+		case "/stall":
+			fmt.Fprint(w, "a")
+			flusher.Flush()
+			time.Sleep(200 * time.Millisecond)
+		case "/steady":
+			// Takes longer than either timeout in total, but never pauses for long
+			for range 8 {
+				fmt.Fprint(w, "a")
+				flusher.Flush()
+				time.Sleep(20 * time.Millisecond)
+			}
+		}
+	}))
+	defer ts.Close()
+
+	tr, delays := testTransport(Timeouts{FirstToken: 100 * time.Millisecond, Idle: 50 * time.Millisecond}, 3)
+	var stall *StallError
+	if _, _, err := get(t, tr, ts.URL+"/slow-start"); !errors.As(err, &stall) || !stall.FirstToken {
+		t.Fatalf("want a first-token stall, got %v", err)
+	}
+	if body, _, err := get(t, tr, ts.URL+"/stall"); !errors.As(err, &stall) || stall.FirstToken || body != "a" {
+		t.Fatalf("want an idle stall after the first chunk, got %q, %v", body, err)
+	}
+	if len(*delays) != 0 {
+		t.Fatalf("stalls must not be retried: %v", *delays)
+	}
+	if body, _, err := get(t, tr, ts.URL+"/steady"); err != nil || body != "aaaaaaaa" {
+		t.Fatalf("steady stream: %q, %v", body, err)
+	}
+}